@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -14,6 +17,37 @@ import (
 func main() {
 	cfg := config.LoadConfig()
 
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(cfg)
+	case "help", "-h", "--help":
+		fmt.Print(config.CommandUsage)
+	default:
+		runCommand(cfg, command, args)
+	}
+}
+
+// runCommand runs an admin subcommand and exits non-zero when it fails.
+func runCommand(cfg *config.ConfigType, command string, args []string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := config.RunCommand(ctx, cfg, command, args, os.Stdout)
+	stop()
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return
+	case errors.Is(err, config.ErrUnknownCommand):
+		fmt.Fprint(os.Stderr, config.CommandUsage)
+	}
+	log.Fatalf("%s failed: %v", command, err)
+}
+
+func serve(cfg *config.ConfigType) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -21,8 +55,14 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	if cfg.MigrateOnStart {
+		if err := config.MigrateDatabase(ctx); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
 	// Application
-	app := config.NewApplication(cfg)
+	app := config.NewApplication(ctx, cfg)
 
 	// Graceful shutdown
 	go func() {
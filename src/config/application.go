@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -17,7 +18,9 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
-func NewApplication(cfg *ConfigType) *ApplicationType {
+// NewApplication sets up the server. ctx bounds the background work the
+// routes start, such as the import workers.
+func NewApplication(ctx context.Context, cfg *ConfigType) *ApplicationType {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -44,7 +47,7 @@ func NewApplication(cfg *ConfigType) *ApplicationType {
 
 	middleware.SetupCorsMiddleware(app)
 
-	setRoutes(app, cfg.APIKey)
+	setRoutes(ctx, app, cfg)
 
 	return application
 }
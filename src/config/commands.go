@@ -0,0 +1,339 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/zombox0633/go_spinsoft/src/station"
+)
+
+const CommandUsage = `usage: go_spinsoft [command] [flags]
+
+commands:
+  serve                             start the HTTP server (default)
+  import --url URL | --file FILE    import stations, --dry-run shows the diff only
+  export --format FORMAT            write stations as geojson, csv, kml or gpx
+  reindex                           rebuild station locations from lat/long
+  migrate up|down|status            apply or roll back MongoDB migrations
+  nearest --lat LAT --long LONG     print the nearest stations
+  validate --file FILE              check a station file without importing it
+
+Run "go_spinsoft <command> -h" for the flags of a command.
+`
+
+// changedBy is the history actor for writes made from the command line.
+const changedBy = "cli"
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+// RunCommand runs one of the admin subcommands with the same services the
+// HTTP API uses. It opens and closes the database itself.
+func RunCommand(ctx context.Context, cfg *ConfigType, name string, args []string, out io.Writer) error {
+	switch name {
+	case "validate":
+		// Validation only parses, it does not need a database
+		return runValidate(ctx, cfg, args, out)
+	case "import", "export", "reindex", "migrate", "nearest":
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownCommand, name)
+	}
+
+	if err := InitDatabase(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer DB.Close(context.Background())
+
+	if name == "migrate" {
+		return RunMigrateCommand(ctx, args, out)
+	}
+
+	stationService, err := newStationService(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "import":
+		return runImport(ctx, stationService, args, out)
+	case "export":
+		return runExport(ctx, stationService, args, out)
+	case "reindex":
+		return runReindex(ctx, stationService, args, out)
+	default:
+		return runNearest(ctx, stationService, args, out)
+	}
+}
+
+func newStationService(ctx context.Context, cfg *ConfigType) (station.StationService, error) {
+	stationRepo, err := newStationRepository(ctx, cfg, DB.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open station store: %w", err)
+	}
+
+	return station.OpenStationService(DB.DBName, station.StationOptions{
+		GeoRulesFile: cfg.GeoRulesFile,
+		Repository:   stationRepo,
+	}), nil
+}
+
+// ---------------------------------- Import -------------------------
+func runImport(ctx context.Context, stationService station.StationService, args []string, out io.Writer) error {
+	flags := newFlagSet("import", out)
+	url := flags.String("url", "", "JSON feed to import")
+	file := flags.String("file", "", "JSON, CSV or GeoJSON file to import")
+	format := flags.String("format", "", "file format, detected from the extension when empty")
+	delimiter := flags.String("delimiter", "", "CSV delimiter")
+	mapping := flags.String("mapping", "", "CSV column mapping as a JSON object of field to column")
+	batchSize := flags.Int("batch-size", 0, "stations per write batch")
+	missingPolicy := flags.String("missing-policy", "", "ignore, deactivate or soft_delete stations missing from the feed")
+	missingComment := flags.String("missing-comment", "", "comment set on deactivated missing stations")
+	dryRun := flags.Bool("dry-run", false, "print the changes without writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if (*url == "") == (*file == "") {
+		return fmt.Errorf("import needs exactly one of --url or --file")
+	}
+
+	opts := station.StationImportOptions{
+		BatchSize:      *batchSize,
+		MissingPolicy:  *missingPolicy,
+		MissingComment: *missingComment,
+	}
+	ctx = station.WithChangedBy(ctx, changedBy)
+
+	if *url != "" {
+		if *dryRun {
+			diff, err := stationService.DiffFromURL(ctx, *url)
+			if err != nil {
+				return err
+			}
+			return writeJSON(out, diff)
+		}
+
+		result, err := stationService.ImportFromURL(ctx, station.StationImportRequest{
+			URL:                  *url,
+			StationImportOptions: opts,
+		})
+		if err != nil {
+			return err
+		}
+		return writeImportResult(out, result)
+	}
+
+	req := station.StationFileImportRequest{
+		Format:               *format,
+		Delimiter:            *delimiter,
+		StationImportOptions: opts,
+	}
+	if *mapping != "" {
+		if err := json.Unmarshal([]byte(*mapping), &req.Mapping); err != nil {
+			return fmt.Errorf("invalid --mapping: must be a JSON object of field to column")
+		}
+	}
+
+	stations, err := parseStationFile(stationService, *file, req)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		diff, err := stationService.DiffStations(ctx, stations)
+		if err != nil {
+			return err
+		}
+		return writeJSON(out, diff)
+	}
+
+	result, err := stationService.ImportStations(ctx, stations, opts)
+	if err != nil {
+		return err
+	}
+	return writeImportResult(out, result)
+}
+
+func writeImportResult(out io.Writer, result *station.StationImportResponse) error {
+	if err := writeJSON(out, result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("import failed: %s", result.Message)
+	}
+	return nil
+}
+
+// ---------------------------------- Export -------------------------
+func runExport(ctx context.Context, stationService station.StationService, args []string, out io.Writer) error {
+	flags := newFlagSet("export", out)
+	format := flags.String("format", "geojson", "geojson, csv, kml or gpx")
+	output := flags.String("out", "", "file to write, stdout when empty")
+	radiusKm := flags.Float64("radius-km", 0, "only stations within this radius of --lat/--long")
+	var lat, long optionalFloat
+	flags.Var(&lat, "lat", "centre latitude for a radius export")
+	flags.Var(&long, "long", "centre longitude for a radius export")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req := station.StationExportRequest{
+		Format:   *format,
+		Lat:      lat.value,
+		Long:     long.value,
+		RadiusKm: *radiusKm,
+	}
+
+	if *output == "" {
+		return stationService.ExportStations(ctx, req, out)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+
+	if err := stationService.ExportStations(ctx, req, file); err != nil {
+		file.Close()
+		os.Remove(*output)
+		return err
+	}
+
+	return file.Close()
+}
+
+// ---------------------------------- Reindex -------------------------
+func runReindex(ctx context.Context, stationService station.StationService, args []string, out io.Writer) error {
+	flags := newFlagSet("reindex", out)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := stationService.ReindexStations(station.WithChangedBy(ctx, changedBy))
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(out, result)
+}
+
+// ---------------------------------- Nearest -------------------------
+func runNearest(ctx context.Context, stationService station.StationService, args []string, out io.Writer) error {
+	flags := newFlagSet("nearest", out)
+	var lat, long optionalFloat
+	flags.Var(&lat, "lat", "latitude")
+	flags.Var(&long, "long", "longitude")
+	limit := flags.Int("limit", 1, "number of stations, 1 to 100")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if lat.value == nil || long.value == nil {
+		return fmt.Errorf("nearest needs --lat and --long")
+	}
+
+	result, err := stationService.FindNearestStation(ctx, station.NearestStationRequest{
+		Lat:   *lat.value,
+		Long:  *long.value,
+		Limit: *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(out, result)
+}
+
+// ---------------------------------- Validate -------------------------
+func runValidate(ctx context.Context, cfg *ConfigType, args []string, out io.Writer) error {
+	flags := newFlagSet("validate", out)
+	file := flags.String("file", "", "JSON, CSV or GeoJSON file to check")
+	format := flags.String("format", "", "file format, detected from the extension when empty")
+	delimiter := flags.String("delimiter", "", "CSV delimiter")
+	mapping := flags.String("mapping", "", "CSV column mapping as a JSON object of field to column")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return fmt.Errorf("validate needs --file")
+	}
+
+	req := station.StationFileImportRequest{
+		Format:    *format,
+		Delimiter: *delimiter,
+	}
+	if *mapping != "" {
+		if err := json.Unmarshal([]byte(*mapping), &req.Mapping); err != nil {
+			return fmt.Errorf("invalid --mapping: must be a JSON object of field to column")
+		}
+	}
+
+	stationService := station.OpenStationService(nil, station.StationOptions{
+		GeoRulesFile: cfg.GeoRulesFile,
+	})
+
+	stations, err := parseStationFile(stationService, *file, req)
+	if err != nil {
+		return err
+	}
+
+	result := stationService.ValidateStations(stations)
+	if err := writeJSON(out, result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("%s: %d rejected, %d duplicate ids", *file, result.RejectedCount, len(result.DuplicateIDs))
+	}
+	return nil
+}
+
+// ---------------------------------- Helpers -------------------------
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	return flags
+}
+
+func parseStationFile(stationService station.StationService, path string, req station.StationFileImportRequest) ([]station.StationModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return stationService.ParseStationFile(filepath.Base(path), file, req)
+}
+
+func writeJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// optionalFloat tells an unset coordinate apart from zero.
+type optionalFloat struct {
+	value *float64
+}
+
+func (f *optionalFloat) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return fmt.Sprint(*f.value)
+}
+
+func (f *optionalFloat) Set(value string) error {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("not a number")
+	}
+	f.value = &parsed
+	return nil
+}
@@ -3,15 +3,31 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	StationStoreMongo    = "mongo"
+	StationStoreMemory   = "memory"
+	StationStorePostgres = "postgres"
+	StationStoreSQLite   = "sqlite"
+)
+
 type ConfigType struct {
-	Port         string
-	APIKey       string
-	MonGoURL     string
-	DataBaseName string
+	Port                string
+	APIKey              string
+	MonGoURL            string
+	DataBaseName        string
+	ImportWorkers       int
+	GeoRulesFile        string
+	FareFile            string
+	StationStore        string
+	StationSnapshotFile string
+	PostgresURL         string
+	SQLiteFile          string
+	MigrateOnStart      bool
 }
 
 func LoadConfig() *ConfigType {
@@ -20,10 +36,18 @@ func LoadConfig() *ConfigType {
 	}
 
 	return &ConfigType{
-		Port:         getEnv("PORT", "8080"),
-		MonGoURL:     getEnv("MONGO_URI", ""),
-		DataBaseName: getEnv("DB_NAME", ""),
-		APIKey:       getEnv("API_KEY", ""),
+		Port:                getEnv("PORT", "8080"),
+		MonGoURL:            getEnv("MONGO_URI", ""),
+		DataBaseName:        getEnv("DB_NAME", ""),
+		APIKey:              getEnv("API_KEY", ""),
+		ImportWorkers:       getEnvInt("IMPORT_WORKERS", 2),
+		GeoRulesFile:        getEnv("GEO_RULES_FILE", "data/geo/rules.json"),
+		FareFile:            getEnv("FARE_FILE", "data/fare/fares.json"),
+		StationStore:        getEnv("STATION_STORE", StationStoreMongo),
+		StationSnapshotFile: getEnv("STATION_SNAPSHOT_FILE", ""),
+		PostgresURL:         getEnv("POSTGRES_URL", ""),
+		SQLiteFile:          getEnv("SQLITE_FILE", "data/station/stations.db"),
+		MigrateOnStart:      getEnvBool("MIGRATE_ON_START", true),
 	}
 }
 
@@ -33,3 +57,31 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %d", key, value, fallback)
+		return fallback
+	}
+	return num
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %t", key, value, fallback)
+		return fallback
+	}
+	return enabled
+}
@@ -2,17 +2,25 @@ package config
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/zombox0633/go_spinsoft/src/station"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	_ "modernc.org/sqlite"
 )
 
 type DatabaseType struct {
-	Client *mongo.Client
-	DBName *mongo.Database
+	Client   *mongo.Client
+	DBName   *mongo.Database
+	Postgres *pgxpool.Pool
+	SQLite   *sql.DB
 }
 
 var DB *DatabaseType
@@ -21,6 +29,23 @@ func InitDatabase(ctx context.Context, cfg *ConfigType) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	// SQLite mode runs offline without MongoDB
+	if cfg.StationStore == StationStoreSQLite {
+		db, err := initSQLite(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		DB = &DatabaseType{SQLite: db}
+		return nil
+	}
+
+	// The memory store can serve stations on its own when MongoDB is not set
+	if cfg.StationStore == StationStoreMemory && cfg.MonGoURL == "" {
+		log.Println("MONGO_URI is empty, running the in-memory station store without MongoDB")
+		DB = &DatabaseType{}
+		return nil
+	}
+
 	if cfg.MonGoURL == "" {
 		return fmt.Errorf("MongoDB URL is empty: check your .env file")
 	}
@@ -43,10 +68,85 @@ func InitDatabase(ctx context.Context, cfg *ConfigType) error {
 	}
 
 	log.Println("Successfully connected to MongoDB")
+
+	// Stations can live in Postgres, everything else stays in MongoDB
+	if cfg.StationStore == StationStorePostgres {
+		pool, err := initPostgres(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		DB.Postgres = pool
+	}
+
 	return nil
 }
 
+func initPostgres(ctx context.Context, cfg *ConfigType) (*pgxpool.Pool, error) {
+	if cfg.PostgresURL == "" {
+		return nil, fmt.Errorf("Postgres URL is empty: set POSTGRES_URL when STATION_STORE=postgres")
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.PostgresURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping Postgres: %w", err)
+	}
+
+	if err := station.MigratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate Postgres: %w", err)
+	}
+
+	log.Println("Successfully connected to Postgres")
+	return pool, nil
+}
+
+func initSQLite(ctx context.Context, cfg *ConfigType) (*sql.DB, error) {
+	if cfg.SQLiteFile == "" {
+		return nil, fmt.Errorf("SQLite file is empty: set SQLITE_FILE when STATION_STORE=sqlite")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.SQLiteFile), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create SQLite directory: %w", err)
+	}
+
+	dsn := "file:" + cfg.SQLiteFile + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite: %w", err)
+	}
+
+	if err := station.MigrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate SQLite: %w", err)
+	}
+
+	log.Printf("Using SQLite database %s", cfg.SQLiteFile)
+	return db, nil
+}
+
 func (d *DatabaseType) Close(ctx context.Context) error {
+	if d.SQLite != nil {
+		log.Println("Closing SQLite database...")
+		if err := d.SQLite.Close(); err != nil {
+			return err
+		}
+	}
+
+	if d.Postgres != nil {
+		log.Println("Closing Postgres connection...")
+		d.Postgres.Close()
+	}
+
 	if d.Client != nil {
 		log.Println("Closing MongoDB connection...")
 		return d.Client.Disconnect(ctx)
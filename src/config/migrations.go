@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/zombox0633/go_spinsoft/src/migrations"
+)
+
+// MigrateDatabase applies pending MongoDB migrations. The SQL stores migrate
+// themselves when they are opened, so there is nothing to do without MongoDB.
+func MigrateDatabase(ctx context.Context) error {
+	if DB == nil || DB.DBName == nil {
+		return nil
+	}
+
+	done, err := migrations.NewMigrator(DB.DBName).Up(ctx, 0)
+	for _, migration := range done {
+		log.Printf("Applied migration %04d %s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate MongoDB: %w", err)
+	}
+
+	return nil
+}
+
+// RunMigrateCommand runs the migrate subcommand against MongoDB.
+func RunMigrateCommand(ctx context.Context, args []string, out io.Writer) error {
+	if DB == nil || DB.DBName == nil {
+		return fmt.Errorf("migrate needs MongoDB: set MONGO_URI")
+	}
+
+	return migrations.RunCommand(ctx, DB.DBName, args, out)
+}
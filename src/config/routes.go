@@ -1,20 +1,26 @@
 package config
 
 import (
+	"context"
+	"log"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/zombox0633/go_spinsoft/src/fare"
+	"github.com/zombox0633/go_spinsoft/src/line"
 	"github.com/zombox0633/go_spinsoft/src/middleware"
 	"github.com/zombox0633/go_spinsoft/src/station"
+	"github.com/zombox0633/go_spinsoft/src/timetable"
 )
 
-func setRoutes(app *fiber.App, apiKey string) {
-	if DB == nil || DB.DBName == nil {
+func setRoutes(ctx context.Context, app *fiber.App, cfg *ConfigType) {
+	if DB == nil || (DB.DBName == nil && DB.SQLite == nil && cfg.StationStore != StationStoreMemory) {
 		panic("Database not initialized")
 	}
 
 	database := DB.DBName
 	api := app.Group("/api")
 
-	api.Use(middleware.APIKeyMiddleware(apiKey))
+	api.Use(middleware.APIKeyMiddleware(cfg.APIKey))
 
 	api.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -22,6 +28,24 @@ func setRoutes(app *fiber.App, apiKey string) {
 		})
 	})
 
+	stationRepo, err := newStationRepository(ctx, cfg, database)
+	if err != nil {
+		log.Fatalf("Failed to open station store: %v", err)
+	}
+
+	// Without MongoDB only the read-only station endpoints are available
+	if database == nil {
+		station.OfflineStationRoutes(api, stationRepo)
+		return
+	}
+
 	// Setup routes
-	station.StationRoutes(api, database)
+	station.StationRoutes(ctx, api, database, station.StationOptions{
+		ImportWorkers: cfg.ImportWorkers,
+		GeoRulesFile:  cfg.GeoRulesFile,
+		Repository:    stationRepo,
+	})
+	line.LineRoutes(api, database)
+	timetable.TimetableRoutes(api, database, stationRepo)
+	fare.FareRoutes(api, database, cfg.FareFile)
 }
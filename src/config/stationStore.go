@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/zombox0633/go_spinsoft/src/station"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// newStationRepository picks the station backend from STATION_STORE. The
+// memory and Postgres stores only replace stations, everything else still
+// uses MongoDB. SQLite, and the memory store without MONGO_URI, run without
+// MongoDB at all.
+func newStationRepository(ctx context.Context, cfg *ConfigType, database *mongo.Database) (station.StationRepository, error) {
+	switch cfg.StationStore {
+	case StationStoreMongo, "":
+		return station.NewStationRepository(database.Collection("stations")), nil
+
+	case StationStoreMemory:
+		repo, err := station.NewMemoryStationRepository(cfg.StationSnapshotFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using in-memory station store loaded from %q", cfg.StationSnapshotFile)
+		return repo, nil
+
+	case StationStorePostgres:
+		if DB.Postgres == nil {
+			return nil, fmt.Errorf("Postgres is not connected")
+		}
+		return station.NewPostgresStationRepository(DB.Postgres), nil
+
+	case StationStoreSQLite:
+		if DB.SQLite == nil {
+			return nil, fmt.Errorf("SQLite is not open")
+		}
+		repo := station.NewSQLiteStationRepository(DB.SQLite)
+		if err := seedStationRepository(ctx, repo, cfg.StationSnapshotFile); err != nil {
+			return nil, err
+		}
+		return repo, nil
+
+	default:
+		return nil, fmt.Errorf("unknown STATION_STORE %q: must be %s, %s, %s or %s",
+			cfg.StationStore, StationStoreMongo, StationStoreMemory, StationStorePostgres, StationStoreSQLite)
+	}
+}
+
+// seedStationRepository loads the snapshot into an empty store, so a kiosk can
+// start from a file shipped with the binary.
+func seedStationRepository(ctx context.Context, repo station.StationRepository, snapshotFile string) error {
+	if snapshotFile == "" {
+		return nil
+	}
+
+	stationIDs, err := repo.FindAllIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(stationIDs) > 0 {
+		return nil
+	}
+
+	stations, err := station.ReadStationSnapshot(snapshotFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.UpsertMany(ctx, stations); err != nil {
+		return err
+	}
+
+	log.Printf("Seeded %d stations from %s", len(stations), snapshotFile)
+	return nil
+}
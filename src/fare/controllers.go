@@ -0,0 +1,64 @@
+package fare
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FareControllerType struct {
+	service FareService
+}
+
+func NewFareController(service FareService) *FareControllerType {
+	return &FareControllerType{
+		service: service,
+	}
+}
+
+// ---------------------------------- Get Fare -------------------------
+func (c *FareControllerType) GetFare(ctx *fiber.Ctx) error {
+	fromStr := ctx.Query("from")
+	toStr := ctx.Query("to")
+
+	if fromStr == "" || toStr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameters: from and to")
+	}
+
+	from, err := strconv.Atoi(fromStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid from station id")
+	}
+
+	to, err := strconv.Atoi(toStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid to station id")
+	}
+
+	req := FareRequest{
+		From:  from,
+		To:    to,
+		Class: ctx.Query("class"),
+	}
+
+	result, err := c.service.CalculateFare(ctx.Context(), req)
+	if err != nil {
+		return fareError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+func fareError(err error) error {
+	switch {
+	case errors.Is(err, ErrStationNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidFareRequest), errors.Is(err, ErrUnknownClass):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrFareTablesMissing):
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
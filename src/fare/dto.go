@@ -0,0 +1,40 @@
+package fare
+
+const (
+	DistanceLine        = "line"
+	DistanceGreatCircle = "great_circle"
+)
+
+type FareRequest struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Class string `json:"class"`
+}
+
+type FareResponse struct {
+	Success        bool       `json:"success"`
+	From           int        `json:"from"`
+	To             int        `json:"to"`
+	DistanceKm     float64    `json:"distance_km"`
+	DistanceMethod string     `json:"distance_method"`
+	LineID         string     `json:"line_id,omitempty"`
+	Currency       string     `json:"currency"`
+	Data           []FareData `json:"data"`
+}
+
+type FareData struct {
+	Class          string         `json:"class"`
+	Name           string         `json:"name"`
+	EnName         string         `json:"en_name"`
+	Fare           float64        `json:"fare"`
+	BaseFare       float64        `json:"base_fare"`
+	MinimumApplied bool           `json:"minimum_applied"`
+	Breakdown      []FareBandData `json:"breakdown"`
+}
+
+type FareBandData struct {
+	FromKm    float64 `json:"from_km"`
+	ToKm      float64 `json:"to_km"`
+	RatePerKm float64 `json:"rate_per_km"`
+	Amount    float64 `json:"amount"`
+}
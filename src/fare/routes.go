@@ -0,0 +1,25 @@
+package fare
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/zombox0633/go_spinsoft/src/line"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func FareRoutes(api fiber.Router, DB *mongo.Database, tablesFile string) {
+	lineRepo := line.NewLineRepository(DB.Collection("lines"), DB.Collection("stations"))
+
+	tables, err := LoadFareTables(tablesFile)
+	if err != nil {
+		log.Printf("Warning: Fares disabled: %v", err)
+	} else {
+		log.Printf("Loaded %d fare classes from %s", len(tables.Classes), tablesFile)
+	}
+
+	fareService := NewFareService(lineRepo, tables)
+	fareController := NewFareController(fareService)
+
+	api.Get("/fare", fareController.GetFare)
+}
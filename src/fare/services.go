@@ -0,0 +1,138 @@
+package fare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/zombox0633/go_spinsoft/src/line"
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+var (
+	ErrInvalidFareRequest = errors.New("invalid fare request")
+	ErrStationNotFound    = errors.New("station not found")
+	ErrFareTablesMissing  = errors.New("fare tables are not loaded")
+)
+
+type FareService interface {
+	CalculateFare(ctx context.Context, req FareRequest) (*FareResponse, error)
+}
+
+type fareServiceType struct {
+	lineRepo line.LineRepository
+	tables   *FareTables
+}
+
+func NewFareService(lineRepo line.LineRepository, tables *FareTables) FareService {
+	return &fareServiceType{
+		lineRepo: lineRepo,
+		tables:   tables,
+	}
+}
+
+// ---------------------------------- Calculate Fare -------------------------
+func (s *fareServiceType) CalculateFare(ctx context.Context, req FareRequest) (*FareResponse, error) {
+	if s.tables == nil {
+		return nil, ErrFareTablesMissing
+	}
+
+	if req.From < 1 || req.To < 1 {
+		return nil, fmt.Errorf("%w: from and to must be station ids greater than 0", ErrInvalidFareRequest)
+	}
+
+	classes := s.tables.Classes
+	if req.Class != "" {
+		class, err := s.tables.find(req.Class)
+		if err != nil {
+			return nil, err
+		}
+		classes = []FareClass{*class}
+	}
+
+	response := &FareResponse{
+		Success:  true,
+		From:     req.From,
+		To:       req.To,
+		Currency: s.tables.Currency,
+		Data:     make([]FareData, 0, len(classes)),
+	}
+
+	if err := s.distance(ctx, req.From, req.To, response); err != nil {
+		return nil, err
+	}
+
+	for _, class := range classes {
+		response.Data = append(response.Data, class.calculate(response.DistanceKm))
+	}
+
+	return response, nil
+}
+
+// distance measures the shortest shared line by the stations' own track
+// positions, as the route graph does, then by the line's chainage. Stations
+// without a shared line fall back to the great circle distance.
+func (s *fareServiceType) distance(ctx context.Context, from, to int, response *FareResponse) error {
+	points, err := s.lineRepo.FindStationPoints(ctx, []int{from, to})
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]line.StationPointModel, len(points))
+	for _, point := range points {
+		byID[point.StationID] = point
+	}
+
+	for _, stationID := range []int{from, to} {
+		if _, ok := byID[stationID]; !ok {
+			return fmt.Errorf("%w: %d", ErrStationNotFound, stationID)
+		}
+	}
+
+	lines, err := s.lineRepo.FindByStation(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	a, b := byID[from], byID[to]
+	trackKm := line.TrackKm(a, b)
+
+	best := math.Inf(1)
+	for _, l := range lines {
+		fromKm, fromOK := chainage(l, from)
+		toKm, toOK := chainage(l, to)
+		if !fromOK || !toOK {
+			continue
+		}
+
+		distance := trackKm
+		if distance == 0 {
+			distance = math.Abs(toKm - fromKm)
+		}
+
+		if distance < best {
+			best = distance
+			response.LineID = l.LineID
+		}
+	}
+
+	if !math.IsInf(best, 1) {
+		response.DistanceKm = roundCents(best)
+		response.DistanceMethod = DistanceLine
+		return nil
+	}
+
+	response.DistanceKm = roundCents(utils.HaversineKm(a.Lat, a.Long, b.Lat, b.Long))
+	response.DistanceMethod = DistanceGreatCircle
+	return nil
+}
+
+func chainage(l line.LineModel, stationID int) (float64, bool) {
+	for _, station := range l.Stations {
+		if station.StationID == stationID {
+			return station.Chainage, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,160 @@
+package fare
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zombox0633/go_spinsoft/src/line"
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+// fakeLineRepository serves the two lookups the fare service makes. Other
+// methods are left to the nil embedded interface and panic if called.
+type fakeLineRepository struct {
+	line.LineRepository
+	lines  []line.LineModel
+	points []line.StationPointModel
+}
+
+func (r *fakeLineRepository) FindByStation(ctx context.Context, stationID int) ([]line.LineModel, error) {
+	var lines []line.LineModel
+	for _, l := range r.lines {
+		for _, station := range l.Stations {
+			if station.StationID == stationID {
+				lines = append(lines, l)
+				break
+			}
+		}
+	}
+	return lines, nil
+}
+
+func (r *fakeLineRepository) FindStationPoints(ctx context.Context, stationIDs []int) ([]line.StationPointModel, error) {
+	var points []line.StationPointModel
+	for _, point := range r.points {
+		for _, stationID := range stationIDs {
+			if point.StationID == stationID {
+				points = append(points, point)
+			}
+		}
+	}
+	return points, nil
+}
+
+func newFixtureLineRepository() *fakeLineRepository {
+	return &fakeLineRepository{
+		lines: []line.LineModel{
+			{
+				LineID: "northern",
+				Stations: []line.LineStationModel{
+					{StationID: 1, Chainage: 0},
+					{StationID: 2, Chainage: 7.5},
+					{StationID: 3, Chainage: 151.25},
+				},
+			},
+			{
+				// A shorter chord between 1 and 3 wins over the main line
+				LineID: "chord",
+				Stations: []line.LineStationModel{
+					{StationID: 1, Chainage: 10},
+					{StationID: 3, Chainage: 130},
+				},
+			},
+		},
+		points: []line.StationPointModel{
+			// 1 and 2 share ExactDistance, 2 and 3 only km posts, and 1 and 3
+			// neither, so that pair is left to the chainage
+			{StationID: 1, Lat: 13.7466, Long: 100.5393, ExactDistance: 100000},
+			{StationID: 2, Lat: 13.8038, Long: 100.5386, ExactDistance: 108250, KM: 108},
+			{StationID: 3, Lat: 14.3532, Long: 100.5689, KM: 251},
+			{StationID: 4, Lat: 13.7505, Long: 100.5612},
+		},
+	}
+}
+
+func TestCalculateFareLineDistance(t *testing.T) {
+	service := NewFareService(newFixtureLineRepository(), loadFixtureTables(t))
+
+	tests := []struct {
+		name     string
+		from, to int
+		lineID   string
+		distance float64
+	}{
+		{name: "exact distance", from: 1, to: 2, lineID: "northern", distance: 8.25},
+		{name: "reverse direction", from: 2, to: 1, lineID: "northern", distance: 8.25},
+		{name: "km posts", from: 2, to: 3, lineID: "northern", distance: 143},
+		{name: "shortest shared line by chainage", from: 1, to: 3, lineID: "chord", distance: 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.CalculateFare(context.Background(), FareRequest{From: tt.from, To: tt.to, Class: "3"})
+			if err != nil {
+				t.Fatalf("CalculateFare: %v", err)
+			}
+
+			if response.DistanceMethod != DistanceLine || response.LineID != tt.lineID {
+				t.Errorf("distance method %q line %q, want %q line %q", response.DistanceMethod, response.LineID, DistanceLine, tt.lineID)
+			}
+			if response.DistanceKm != tt.distance {
+				t.Errorf("distance_km = %v, want %v", response.DistanceKm, tt.distance)
+			}
+			if len(response.Data) != 1 || response.Data[0].Class != "3" {
+				t.Errorf("data = %+v, want only class 3", response.Data)
+			}
+		})
+	}
+}
+
+func TestCalculateFareGreatCircleFallback(t *testing.T) {
+	service := NewFareService(newFixtureLineRepository(), loadFixtureTables(t))
+
+	response, err := service.CalculateFare(context.Background(), FareRequest{From: 1, To: 4})
+	if err != nil {
+		t.Fatalf("CalculateFare: %v", err)
+	}
+
+	want := roundCents(utils.HaversineKm(13.7466, 100.5393, 13.7505, 100.5612))
+	if response.DistanceMethod != DistanceGreatCircle || response.LineID != "" {
+		t.Errorf("distance method %q line %q, want %q without a line", response.DistanceMethod, response.LineID, DistanceGreatCircle)
+	}
+	if response.DistanceKm != want {
+		t.Errorf("distance_km = %v, want %v", response.DistanceKm, want)
+	}
+
+	// Without a class every class is priced
+	if len(response.Data) != 3 {
+		t.Fatalf("got %d classes, want 3", len(response.Data))
+	}
+	if response.Currency != "THB" {
+		t.Errorf("currency = %q, want THB", response.Currency)
+	}
+}
+
+func TestCalculateFareErrors(t *testing.T) {
+	repo := newFixtureLineRepository()
+	tables := loadFixtureTables(t)
+
+	tests := []struct {
+		name    string
+		service FareService
+		req     FareRequest
+		wantErr error
+	}{
+		{name: "tables not loaded", service: NewFareService(repo, nil), req: FareRequest{From: 1, To: 2}, wantErr: ErrFareTablesMissing},
+		{name: "missing from", service: NewFareService(repo, tables), req: FareRequest{To: 2}, wantErr: ErrInvalidFareRequest},
+		{name: "unknown class", service: NewFareService(repo, tables), req: FareRequest{From: 1, To: 2, Class: "9"}, wantErr: ErrUnknownClass},
+		{name: "unknown station", service: NewFareService(repo, tables), req: FareRequest{From: 1, To: 99}, wantErr: ErrStationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.service.CalculateFare(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CalculateFare() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
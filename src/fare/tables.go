@@ -0,0 +1,154 @@
+package fare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+var ErrUnknownClass = errors.New("unknown fare class")
+
+// FareBand charges RatePerKm for the distance between the previous band's
+// limit and UpToKm. The last band has no limit.
+type FareBand struct {
+	UpToKm    float64 `json:"up_to_km,omitempty"`
+	RatePerKm float64 `json:"rate_per_km"`
+}
+
+type FareClass struct {
+	Class       string     `json:"class"`
+	Name        string     `json:"name"`
+	EnName      string     `json:"en_name"`
+	BaseFare    float64    `json:"base_fare"`
+	MinimumFare float64    `json:"minimum_fare"`
+	RoundUpTo   float64    `json:"round_up_to"`
+	Bands       []FareBand `json:"bands"`
+}
+
+type FareTables struct {
+	Currency string      `json:"currency"`
+	Classes  []FareClass `json:"classes"`
+}
+
+// ---------------------------------- Load -------------------------
+func LoadFareTables(path string) (*FareTables, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fare tables: %w", err)
+	}
+
+	var tables FareTables
+	if err := json.Unmarshal(data, &tables); err != nil {
+		return nil, fmt.Errorf("failed to parse fare tables: %w", err)
+	}
+
+	if err := tables.validate(); err != nil {
+		return nil, err
+	}
+
+	return &tables, nil
+}
+
+func (t *FareTables) validate() error {
+	if len(t.Classes) == 0 {
+		return fmt.Errorf("fare tables have no classes")
+	}
+
+	seen := make(map[string]bool, len(t.Classes))
+	for _, class := range t.Classes {
+		if class.Class == "" {
+			return fmt.Errorf("fare class is missing its class code")
+		}
+		if seen[class.Class] {
+			return fmt.Errorf("fare class %q is defined more than once", class.Class)
+		}
+		seen[class.Class] = true
+
+		if len(class.Bands) == 0 {
+			return fmt.Errorf("fare class %q has no bands", class.Class)
+		}
+
+		limit := 0.0
+		for i, band := range class.Bands {
+			if band.RatePerKm < 0 {
+				return fmt.Errorf("fare class %q band %d: rate_per_km must not be negative", class.Class, i)
+			}
+
+			last := i == len(class.Bands)-1
+			if !last && band.UpToKm <= limit {
+				return fmt.Errorf("fare class %q band %d: up_to_km must be greater than %v", class.Class, i, limit)
+			}
+			if last && band.UpToKm != 0 {
+				return fmt.Errorf("fare class %q: the last band must not set up_to_km", class.Class)
+			}
+			limit = band.UpToKm
+		}
+	}
+
+	return nil
+}
+
+func (t *FareTables) find(class string) (*FareClass, error) {
+	for i := range t.Classes {
+		if t.Classes[i].Class == class {
+			return &t.Classes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownClass, class)
+}
+
+// ---------------------------------- Calculate -------------------------
+
+// calculate prices distanceKm by walking the bands, then applies the minimum
+// fare and rounds up.
+func (c *FareClass) calculate(distanceKm float64) FareData {
+	data := FareData{
+		Class:     c.Class,
+		Name:      c.Name,
+		EnName:    c.EnName,
+		BaseFare:  c.BaseFare,
+		Breakdown: make([]FareBandData, 0, len(c.Bands)),
+	}
+
+	amount := c.BaseFare
+	from := 0.0
+	for _, band := range c.Bands {
+		if distanceKm <= from {
+			break
+		}
+
+		to := distanceKm
+		if band.UpToKm > 0 && band.UpToKm < distanceKm {
+			to = band.UpToKm
+		}
+
+		bandAmount := (to - from) * band.RatePerKm
+		amount += bandAmount
+		data.Breakdown = append(data.Breakdown, FareBandData{
+			FromKm:    from,
+			ToKm:      to,
+			RatePerKm: band.RatePerKm,
+			Amount:    roundCents(bandAmount),
+		})
+
+		from = to
+	}
+
+	if amount < c.MinimumFare {
+		amount = c.MinimumFare
+		data.MinimumApplied = true
+	}
+
+	if c.RoundUpTo > 0 {
+		amount = math.Ceil(roundCents(amount)/c.RoundUpTo) * c.RoundUpTo
+	}
+
+	data.Fare = roundCents(amount)
+	return data
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,219 @@
+package fare
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fixtureFaresFile = "testdata/fares.json"
+
+func loadFixtureTables(t *testing.T) *FareTables {
+	t.Helper()
+
+	tables, err := LoadFareTables(fixtureFaresFile)
+	if err != nil {
+		t.Fatalf("LoadFareTables: %v", err)
+	}
+	return tables
+}
+
+func fixtureClass(t *testing.T, tables *FareTables, code string) *FareClass {
+	t.Helper()
+
+	class, err := tables.find(code)
+	if err != nil {
+		t.Fatalf("find(%q): %v", code, err)
+	}
+	return class
+}
+
+func TestFareClassCalculate(t *testing.T) {
+	tables := loadFixtureTables(t)
+
+	tests := []struct {
+		name           string
+		class          string
+		distanceKm     float64
+		fare           float64
+		minimumApplied bool
+		breakdown      []FareBandData
+	}{
+		{
+			name: "zero distance charges the minimum", class: "3", distanceKm: 0,
+			fare: 2, minimumApplied: true, breakdown: []FareBandData{},
+		},
+		{
+			name: "short trip below the minimum", class: "3", distanceKm: 5,
+			fare: 2, minimumApplied: true,
+			breakdown: []FareBandData{{FromKm: 0, ToKm: 5, RatePerKm: 0.24, Amount: 1.2}},
+		},
+		{
+			name: "ends exactly on a band limit", class: "3", distanceKm: 100,
+			fare:      24,
+			breakdown: []FareBandData{{FromKm: 0, ToKm: 100, RatePerKm: 0.24, Amount: 24}},
+		},
+		{
+			name: "walks into the second band and rounds up", class: "3", distanceKm: 150,
+			fare: 35,
+			breakdown: []FareBandData{
+				{FromKm: 0, ToKm: 100, RatePerKm: 0.24, Amount: 24},
+				{FromKm: 100, ToKm: 150, RatePerKm: 0.21, Amount: 10.5},
+			},
+		},
+		{
+			name: "walks every band", class: "3", distanceKm: 400,
+			fare: 83,
+			breakdown: []FareBandData{
+				{FromKm: 0, ToKm: 100, RatePerKm: 0.24, Amount: 24},
+				{FromKm: 100, ToKm: 300, RatePerKm: 0.21, Amount: 42},
+				{FromKm: 300, ToKm: 400, RatePerKm: 0.17, Amount: 17},
+			},
+		},
+		{
+			name: "base fare counts towards the minimum", class: "1", distanceKm: 20,
+			fare: 100, minimumApplied: true,
+			breakdown: []FareBandData{{FromKm: 0, ToKm: 20, RatePerKm: 1.10, Amount: 22}},
+		},
+		{
+			name: "rounds up to the next 10", class: "1", distanceKm: 350,
+			fare: 430,
+			breakdown: []FareBandData{
+				{FromKm: 0, ToKm: 300, RatePerKm: 1.10, Amount: 330},
+				{FromKm: 300, ToKm: 350, RatePerKm: 0.95, Amount: 47.5},
+			},
+		},
+		{
+			name: "without round_up_to only cents are rounded", class: "exact", distanceKm: 12,
+			fare: 4.33,
+			breakdown: []FareBandData{
+				{FromKm: 0, ToKm: 10, RatePerKm: 0.333, Amount: 3.33},
+				{FromKm: 10, ToKm: 12, RatePerKm: 0.5, Amount: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fixtureClass(t, tables, tt.class).calculate(tt.distanceKm)
+
+			if data.Fare != tt.fare {
+				t.Errorf("fare = %v, want %v", data.Fare, tt.fare)
+			}
+			if data.MinimumApplied != tt.minimumApplied {
+				t.Errorf("minimum_applied = %v, want %v", data.MinimumApplied, tt.minimumApplied)
+			}
+			if len(data.Breakdown) != len(tt.breakdown) {
+				t.Fatalf("breakdown = %+v, want %+v", data.Breakdown, tt.breakdown)
+			}
+			for i := range tt.breakdown {
+				if data.Breakdown[i] != tt.breakdown[i] {
+					t.Errorf("breakdown[%d] = %+v, want %+v", i, data.Breakdown[i], tt.breakdown[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFareTablesValidate(t *testing.T) {
+	band := func(upTo, rate float64) FareBand {
+		return FareBand{UpToKm: upTo, RatePerKm: rate}
+	}
+
+	tests := []struct {
+		name    string
+		classes []FareClass
+		wantErr string
+	}{
+		{
+			name:    "no classes",
+			wantErr: "no classes",
+		},
+		{
+			name:    "missing class code",
+			classes: []FareClass{{Bands: []FareBand{band(0, 1)}}},
+			wantErr: "missing its class code",
+		},
+		{
+			name: "duplicate class",
+			classes: []FareClass{
+				{Class: "3", Bands: []FareBand{band(0, 1)}},
+				{Class: "3", Bands: []FareBand{band(0, 1)}},
+			},
+			wantErr: "defined more than once",
+		},
+		{
+			name:    "no bands",
+			classes: []FareClass{{Class: "3"}},
+			wantErr: "has no bands",
+		},
+		{
+			name:    "negative rate",
+			classes: []FareClass{{Class: "3", Bands: []FareBand{band(0, -0.1)}}},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "band limits not increasing",
+			classes: []FareClass{{Class: "3", Bands: []FareBand{band(100, 1), band(100, 1), band(0, 1)}}},
+			wantErr: "up_to_km must be greater than 100",
+		},
+		{
+			name:    "first band without a limit",
+			classes: []FareClass{{Class: "3", Bands: []FareBand{band(0, 1), band(0, 1)}}},
+			wantErr: "up_to_km must be greater than 0",
+		},
+		{
+			name:    "last band with a limit",
+			classes: []FareClass{{Class: "3", Bands: []FareBand{band(100, 1), band(200, 1)}}},
+			wantErr: "last band must not set up_to_km",
+		},
+		{
+			name:    "valid",
+			classes: []FareClass{{Class: "3", Bands: []FareBand{band(100, 1), band(0, 0)}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&FareTables{Classes: tt.classes}).validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFareTables(t *testing.T) {
+	tables := loadFixtureTables(t)
+	if tables.Currency != "THB" || len(tables.Classes) != 3 {
+		t.Fatalf("loaded %s with %d classes, want THB with 3", tables.Currency, len(tables.Classes))
+	}
+
+	if _, err := LoadFareTables(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: want an error")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"classes": [}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFareTables(invalid); err == nil {
+		t.Error("invalid JSON: want an error")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(empty, []byte(`{"currency": "THB", "classes": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFareTables(empty); err == nil {
+		t.Error("no classes: want a validation error")
+	}
+}
@@ -0,0 +1,126 @@
+package georules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type geoJSONFile struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+	Geometry *geoJSONGeometry `json:"geometry"`
+}
+
+type geoJSONFeature struct {
+	Geometry *geoJSONGeometry `json:"geometry"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// readGeometries accepts a FeatureCollection, a single Feature or a bare geometry.
+func readGeometries(path string) ([]geoJSONGeometry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var file geoJSONFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	switch file.Type {
+	case "FeatureCollection":
+		geometries := make([]geoJSONGeometry, 0, len(file.Features))
+		for _, feature := range file.Features {
+			if feature.Geometry != nil {
+				geometries = append(geometries, *feature.Geometry)
+			}
+		}
+		return geometries, nil
+	case "Feature":
+		if file.Geometry == nil {
+			return nil, nil
+		}
+		return []geoJSONGeometry{*file.Geometry}, nil
+	default:
+		var geometry geoJSONGeometry
+		if err := json.Unmarshal(data, &geometry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return []geoJSONGeometry{geometry}, nil
+	}
+}
+
+func loadPolygons(path string) ([]polygon, bbox, error) {
+	geometries, err := readGeometries(path)
+	if err != nil {
+		return nil, bbox{}, err
+	}
+
+	var polygons []polygon
+	for _, geometry := range geometries {
+		switch geometry.Type {
+		case "Polygon":
+			var p polygon
+			if err := json.Unmarshal(geometry.Coordinates, &p); err != nil {
+				return nil, bbox{}, fmt.Errorf("invalid Polygon in %s: %w", path, err)
+			}
+			polygons = append(polygons, p)
+		case "MultiPolygon":
+			var ps []polygon
+			if err := json.Unmarshal(geometry.Coordinates, &ps); err != nil {
+				return nil, bbox{}, fmt.Errorf("invalid MultiPolygon in %s: %w", path, err)
+			}
+			polygons = append(polygons, ps...)
+		}
+	}
+
+	if len(polygons) == 0 {
+		return nil, bbox{}, fmt.Errorf("no Polygon or MultiPolygon found in %s", path)
+	}
+
+	bounds := newBBox()
+	for _, p := range polygons {
+		for _, point := range p[0] {
+			bounds.extend(point[0], point[1])
+		}
+	}
+
+	return polygons, bounds, nil
+}
+
+func loadPolylines(path string) ([]polyline, error) {
+	geometries, err := readGeometries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []polyline
+	for _, geometry := range geometries {
+		switch geometry.Type {
+		case "LineString":
+			var l polyline
+			if err := json.Unmarshal(geometry.Coordinates, &l); err != nil {
+				return nil, fmt.Errorf("invalid LineString in %s: %w", path, err)
+			}
+			lines = append(lines, l)
+		case "MultiLineString":
+			var ls []polyline
+			if err := json.Unmarshal(geometry.Coordinates, &ls); err != nil {
+				return nil, fmt.Errorf("invalid MultiLineString in %s: %w", path, err)
+			}
+			lines = append(lines, ls...)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no LineString or MultiLineString found in %s", path)
+	}
+
+	return lines, nil
+}
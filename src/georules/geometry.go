@@ -0,0 +1,110 @@
+package georules
+
+import (
+	"math"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+// Polygon rings are [long, lat] pairs; the first ring is the outer boundary
+// and the rest are holes.
+type polygon [][][2]float64
+
+type polyline [][2]float64
+
+type bbox struct {
+	minLong, minLat, maxLong, maxLat float64
+}
+
+func newBBox() bbox {
+	return bbox{
+		minLong: math.Inf(1),
+		minLat:  math.Inf(1),
+		maxLong: math.Inf(-1),
+		maxLat:  math.Inf(-1),
+	}
+}
+
+func (b *bbox) extend(long, lat float64) {
+	b.minLong = math.Min(b.minLong, long)
+	b.minLat = math.Min(b.minLat, lat)
+	b.maxLong = math.Max(b.maxLong, long)
+	b.maxLat = math.Max(b.maxLat, lat)
+}
+
+func (b bbox) contains(lat, long float64) bool {
+	return long >= b.minLong && long <= b.maxLong && lat >= b.minLat && lat <= b.maxLat
+}
+
+// ---------------------------------- Point In Polygon -------------------------
+func (p polygon) contains(lat, long float64) bool {
+	if len(p) == 0 || !ringContains(p[0], lat, long) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if ringContains(hole, lat, long) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains uses ray casting on the planar long/lat coordinates.
+func ringContains(ring [][2]float64, lat, long float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > lat) != (yj > lat) && long < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ---------------------------------- Distance To Line -------------------------
+
+// distanceMetres returns the distance from the point to the closest segment of
+// the line, projecting locally around the point (accurate for short distances).
+func (l polyline) distanceMetres(lat, long float64) float64 {
+	if len(l) == 0 {
+		return math.Inf(1)
+	}
+
+	metresPerDegree := utils.EarthRadiusKm * 1000 * math.Pi / 180
+	scaleX := metresPerDegree * math.Cos(lat*math.Pi/180)
+
+	project := func(point [2]float64) (float64, float64) {
+		return (point[0] - long) * scaleX, (point[1] - lat) * metresPerDegree
+	}
+
+	if len(l) == 1 {
+		x, y := project(l[0])
+		return math.Hypot(x, y)
+	}
+
+	best := math.Inf(1)
+	for i := 1; i < len(l); i++ {
+		x1, y1 := project(l[i-1])
+		x2, y2 := project(l[i])
+		best = math.Min(best, segmentDistance(x1, y1, x2, y2))
+	}
+
+	return best
+}
+
+// segmentDistance is the distance from the origin to the segment.
+func segmentDistance(x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	if dx == 0 && dy == 0 {
+		return math.Hypot(x1, y1)
+	}
+
+	t := -(x1*dx + y1*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+
+	return math.Hypot(x1+t*dx, y1+t*dy)
+}
@@ -0,0 +1,282 @@
+package georules
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+type Action string
+
+const (
+	ActionReject     Action = "reject"
+	ActionDeactivate Action = "deactivate"
+	ActionWarn       Action = "warn"
+)
+
+const (
+	RuleCountry  = "country"
+	RuleRailLine = "rail_line"
+	RuleSwapped  = "swapped"
+)
+
+type Violation struct {
+	Rule   string `json:"rule"`
+	Action Action `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// RuleConfig is one entry of the rules file. File paths are resolved
+// relative to the rules file.
+type RuleConfig struct {
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Action       Action  `json:"action"`
+	File         string  `json:"file"`
+	MaxDistanceM float64 `json:"max_distance_m"`
+	Disabled     bool    `json:"disabled"`
+}
+
+type rulesFile struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+type rule interface {
+	check(lat, long float64) (string, bool)
+}
+
+type configuredRule struct {
+	name   string
+	action Action
+	rule   rule
+}
+
+type Engine struct {
+	rules []configuredRule
+}
+
+// ---------------------------------- Load -------------------------
+func LoadEngine(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geo rules: %w", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse geo rules: %w", err)
+	}
+
+	return NewEngine(file.Rules, filepath.Dir(path))
+}
+
+func NewEngine(configs []RuleConfig, baseDir string) (*Engine, error) {
+	engine := &Engine{}
+
+	for _, config := range configs {
+		if config.Disabled {
+			continue
+		}
+
+		switch config.Action {
+		case ActionReject, ActionDeactivate, ActionWarn:
+		default:
+			return nil, fmt.Errorf("rule %q: invalid action %q: must be reject, deactivate or warn", config.Type, config.Action)
+		}
+
+		file := config.File
+		if file != "" && !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+
+		var r rule
+		var err error
+
+		switch config.Type {
+		case RuleCountry:
+			r, err = newCountryRule(file)
+		case RuleRailLine:
+			r, err = newRailLineRule(file, config.MaxDistanceM)
+		case RuleSwapped:
+			r, err = newSwappedRule(file)
+		default:
+			err = fmt.Errorf("unknown rule type")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", config.Type, err)
+		}
+
+		name := config.Name
+		if name == "" {
+			name = config.Type
+		}
+
+		engine.rules = append(engine.rules, configuredRule{
+			name:   name,
+			action: config.Action,
+			rule:   r,
+		})
+	}
+
+	return engine, nil
+}
+
+// ---------------------------------- Evaluate -------------------------
+
+// Evaluate runs every rule against the point. A nil engine has no rules.
+func (e *Engine) Evaluate(lat, long float64) []Violation {
+	if e == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, r := range e.rules {
+		if reason, violated := r.rule.check(lat, long); violated {
+			violations = append(violations, Violation{
+				Rule:   r.name,
+				Action: r.action,
+				Reason: reason,
+			})
+		}
+	}
+
+	return violations
+}
+
+func (e *Engine) Len() int {
+	if e == nil {
+		return 0
+	}
+	return len(e.rules)
+}
+
+func inGlobalBounds(lat, long float64) bool {
+	return lat >= -90 && lat <= 90 && long >= -180 && long <= 180 && !(lat == 0 && long == 0)
+}
+
+// ---------------------------------- Country -------------------------
+type countryRule struct {
+	area *area
+}
+
+func newCountryRule(file string) (*countryRule, error) {
+	if file == "" {
+		return nil, fmt.Errorf("file is required")
+	}
+
+	a, err := loadArea(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &countryRule{area: a}, nil
+}
+
+func (r *countryRule) check(lat, long float64) (string, bool) {
+	// Out of range coordinates are already reported by the bounds check
+	if !inGlobalBounds(lat, long) || r.area.contains(lat, long) {
+		return "", false
+	}
+	return fmt.Sprintf("Coordinates (%v, %v) are outside %s", lat, long, r.area.name), true
+}
+
+type area struct {
+	name     string
+	polygons []polygon
+	bounds   bbox
+}
+
+func loadArea(file string) (*area, error) {
+	polygons, bounds, err := loadPolygons(file)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(file)
+	return &area{name: name[:len(name)-len(filepath.Ext(name))], polygons: polygons, bounds: bounds}, nil
+}
+
+func (a *area) contains(lat, long float64) bool {
+	if !a.bounds.contains(lat, long) {
+		return false
+	}
+
+	for _, p := range a.polygons {
+		if p.contains(lat, long) {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------------- Rail Line -------------------------
+type railLineRule struct {
+	lines        []polyline
+	maxDistanceM float64
+}
+
+func newRailLineRule(file string, maxDistanceM float64) (*railLineRule, error) {
+	if file == "" {
+		return nil, fmt.Errorf("file is required")
+	}
+	if maxDistanceM <= 0 {
+		return nil, fmt.Errorf("max_distance_m must be greater than 0")
+	}
+
+	lines, err := loadPolylines(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &railLineRule{lines: lines, maxDistanceM: maxDistanceM}, nil
+}
+
+func (r *railLineRule) check(lat, long float64) (string, bool) {
+	if !inGlobalBounds(lat, long) {
+		return "", false
+	}
+
+	closest := math.Inf(1)
+	for _, line := range r.lines {
+		closest = math.Min(closest, line.distanceMetres(lat, long))
+		if closest <= r.maxDistanceM {
+			return "", false
+		}
+	}
+
+	return fmt.Sprintf("Coordinates are %.0f m from the nearest rail line (max %.0f m)", closest, r.maxDistanceM), true
+}
+
+// ---------------------------------- Swapped -------------------------
+type swappedRule struct {
+	area *area
+}
+
+// newSwappedRule uses the optional area to spot points that only fall inside
+// it once lat and long are exchanged.
+func newSwappedRule(file string) (*swappedRule, error) {
+	if file == "" {
+		return &swappedRule{}, nil
+	}
+
+	a, err := loadArea(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &swappedRule{area: a}, nil
+}
+
+func (r *swappedRule) check(lat, long float64) (string, bool) {
+	if math.Abs(lat) > 90 && math.Abs(long) <= 90 {
+		return fmt.Sprintf("Latitude %v and longitude %v look swapped", lat, long), true
+	}
+
+	if r.area != nil && inGlobalBounds(long, lat) && !r.area.contains(lat, long) && r.area.contains(long, lat) {
+		return fmt.Sprintf("Latitude %v and longitude %v look swapped: swapped point is inside %s", lat, long, r.area.name), true
+	}
+
+	return "", false
+}
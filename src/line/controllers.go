@@ -0,0 +1,164 @@
+package line
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LineControllerType struct {
+	service LineService
+}
+
+func NewLineController(service LineService) *LineControllerType {
+	return &LineControllerType{
+		service: service,
+	}
+}
+
+// ---------------------------------- Get Lines -------------------------
+func (c *LineControllerType) GetLines(ctx *fiber.Ctx) error {
+	if ctx.Query("station_id") != "" {
+		stationID := ctx.QueryInt("station_id")
+		if stationID < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid station_id")
+		}
+
+		result, err := c.service.GetStationLines(ctx.Context(), stationID)
+		if err != nil {
+			return lineError(err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(result)
+	}
+
+	result, err := c.service.ListLines(ctx.Context())
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Line -------------------------
+func (c *LineControllerType) GetLine(ctx *fiber.Ctx) error {
+	result, err := c.service.GetLine(ctx.Context(), ctx.Params("lineId"))
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Line Stations -------------------------
+func (c *LineControllerType) GetLineStations(ctx *fiber.Ctx) error {
+	result, err := c.service.GetLineStations(ctx.Context(), ctx.Params("lineId"))
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Station Lines -------------------------
+func (c *LineControllerType) GetStationLines(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	result, err := c.service.GetStationLines(ctx.Context(), stationID)
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Post Line -------------------------
+func (c *LineControllerType) PostLine(ctx *fiber.Ctx) error {
+	var req LineRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.service.CreateLine(ctx.Context(), req)
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(result)
+}
+
+// ---------------------------------- Put Line -------------------------
+func (c *LineControllerType) PutLine(ctx *fiber.Ctx) error {
+	var req LineRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.service.ReplaceLine(ctx.Context(), ctx.Params("lineId"), req)
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Delete Line -------------------------
+func (c *LineControllerType) DeleteLine(ctx *fiber.Ctx) error {
+	result, err := c.service.DeleteLine(ctx.Context(), ctx.Params("lineId"))
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Route -------------------------
+func (c *LineControllerType) GetRoute(ctx *fiber.Ctx) error {
+	fromStr := ctx.Query("from")
+	toStr := ctx.Query("to")
+
+	if fromStr == "" || toStr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameters: from and to")
+	}
+
+	from, err := strconv.Atoi(fromStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid from station id")
+	}
+
+	to, err := strconv.Atoi(toStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid to station id")
+	}
+
+	req := RouteRequest{
+		From: from,
+		To:   to,
+	}
+
+	result, err := c.service.FindRoute(ctx.Context(), req)
+	if err != nil {
+		return lineError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+func lineError(err error) error {
+	switch {
+	case errors.Is(err, ErrLineNotFound), errors.Is(err, ErrStationNotOnNetwork), errors.Is(err, ErrNoRoute):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrLineExists):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidLine):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
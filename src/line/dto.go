@@ -0,0 +1,121 @@
+package line
+
+// Line Create / Update
+type LineRequest struct {
+	LineID   string               `json:"line_id"`
+	Name     string               `json:"name"`
+	EnName   string               `json:"en_name"`
+	Color    string               `json:"color"`
+	Stations []LineStationRequest `json:"stations"`
+}
+
+type LineStationRequest struct {
+	StationID int      `json:"station_id"`
+	Chainage  *float64 `json:"chainage_km,omitempty"`
+}
+
+type LineResponse struct {
+	Success bool       `json:"success"`
+	Data    *LineModel `json:"data"`
+}
+
+type LineDeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Line List
+type LineListResponse struct {
+	Success bool              `json:"success"`
+	Data    []LineSummaryData `json:"data"`
+}
+
+type LineSummaryData struct {
+	LineID       string  `json:"line_id"`
+	Name         string  `json:"name"`
+	EnName       string  `json:"en_name"`
+	Color        string  `json:"color"`
+	StationCount int     `json:"station_count"`
+	LengthKm     float64 `json:"length_km"`
+}
+
+// Line Stations
+type LineStationsResponse struct {
+	Success bool              `json:"success"`
+	LineID  string            `json:"line_id"`
+	Name    string            `json:"name"`
+	EnName  string            `json:"en_name"`
+	Data    []LineStationData `json:"data"`
+}
+
+type LineStationData struct {
+	Sequence  int     `bson:"sequence" json:"sequence"`
+	StationID int     `bson:"station_id" json:"station_id"`
+	Chainage  float64 `bson:"chainage_km" json:"chainage_km"`
+	Name      string  `bson:"name" json:"name"`
+	EnName    string  `bson:"en_name" json:"en_name"`
+	Lat       float64 `bson:"lat" json:"lat"`
+	Long      float64 `bson:"long" json:"long"`
+	Active    int     `bson:"active" json:"active"`
+}
+
+// Station Lines
+type StationLinesResponse struct {
+	Success   bool              `json:"success"`
+	StationID int               `json:"station_id"`
+	Data      []StationLineData `json:"data"`
+}
+
+type StationLineData struct {
+	LineID   string  `json:"line_id"`
+	Name     string  `json:"name"`
+	EnName   string  `json:"en_name"`
+	Color    string  `json:"color"`
+	Sequence int     `json:"sequence"`
+	Chainage float64 `json:"chainage_km"`
+}
+
+// Route
+type RouteRequest struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type RouteResponse struct {
+	Success      bool               `json:"success"`
+	From         int                `json:"from"`
+	To           int                `json:"to"`
+	DistanceKm   float64            `json:"distance_km"`
+	Stations     []RouteStationData `json:"stations"`
+	Legs         []RouteLegData     `json:"legs"`
+	Interchanges []RouteInterchange `json:"interchanges"`
+}
+
+type RouteStationData struct {
+	Sequence   int     `json:"sequence"`
+	StationID  int     `json:"station_id"`
+	Name       string  `json:"name"`
+	EnName     string  `json:"en_name"`
+	Lat        float64 `json:"lat"`
+	Long       float64 `json:"long"`
+	LineID     string  `json:"line_id"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+type RouteLegData struct {
+	LineID        string  `json:"line_id"`
+	Name          string  `json:"name"`
+	EnName        string  `json:"en_name"`
+	FromStationID int     `json:"from_station_id"`
+	ToStationID   int     `json:"to_station_id"`
+	StationCount  int     `json:"station_count"`
+	DistanceKm    float64 `json:"distance_km"`
+}
+
+type RouteInterchange struct {
+	StationID  int    `json:"station_id"`
+	Name       string `json:"name"`
+	EnName     string `json:"en_name"`
+	FromLineID string `json:"from_line_id"`
+	ToLineID   string `json:"to_line_id"`
+}
@@ -0,0 +1,213 @@
+package line
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+type routeEdge struct {
+	to         int
+	lineID     string
+	distanceKm float64
+}
+
+// routeGraph connects adjacent stations on every line. Edge weights come from
+// the stations' own ExactDistance or KM, then the line's chainage, and finally
+// the great circle distance when both share the same km post.
+type routeGraph struct {
+	stations  map[int]StationPointModel
+	lines     map[string]LineModel
+	adjacency map[int][]routeEdge
+}
+
+func newRouteGraph(lines []LineModel, points []StationPointModel) *routeGraph {
+	g := &routeGraph{
+		stations:  make(map[int]StationPointModel, len(points)),
+		lines:     make(map[string]LineModel, len(lines)),
+		adjacency: make(map[int][]routeEdge),
+	}
+
+	for _, point := range points {
+		g.stations[point.StationID] = point
+	}
+
+	for _, line := range lines {
+		g.lines[line.LineID] = line
+
+		// Deleted stations are skipped and their neighbours joined directly
+		var previous *LineStationModel
+		for i := range line.Stations {
+			current := &line.Stations[i]
+			if _, ok := g.stations[current.StationID]; !ok {
+				continue
+			}
+
+			if previous != nil {
+				distance := TrackKm(g.stations[previous.StationID], g.stations[current.StationID])
+				if distance == 0 {
+					distance = math.Abs(current.Chainage - previous.Chainage)
+				}
+				if distance == 0 {
+					distance = g.straightLineKm(previous.StationID, current.StationID)
+				}
+
+				g.adjacency[previous.StationID] = append(g.adjacency[previous.StationID], routeEdge{
+					to:         current.StationID,
+					lineID:     line.LineID,
+					distanceKm: distance,
+				})
+				g.adjacency[current.StationID] = append(g.adjacency[current.StationID], routeEdge{
+					to:         previous.StationID,
+					lineID:     line.LineID,
+					distanceKm: distance,
+				})
+			}
+			previous = current
+		}
+	}
+
+	return g
+}
+
+func (g *routeGraph) hasStation(stationID int) bool {
+	_, ok := g.adjacency[stationID]
+	return ok
+}
+
+// TrackKm is the distance between two stations from their station data:
+// ExactDistance when both have it, otherwise their km posts. It is 0 when
+// neither is set on both.
+func TrackKm(a, b StationPointModel) float64 {
+	if a.ExactDistance > 0 && b.ExactDistance > 0 {
+		return math.Abs(float64(a.ExactDistance-b.ExactDistance)) / 1000
+	}
+	if a.KM > 0 && b.KM > 0 {
+		return math.Abs(float64(a.KM - b.KM))
+	}
+	return 0
+}
+
+// straightLineKm is 0 when either station has no coordinates.
+func (g *routeGraph) straightLineKm(from, to int) float64 {
+	a, b := g.stations[from], g.stations[to]
+	if (a.Lat == 0 && a.Long == 0) || (b.Lat == 0 && b.Long == 0) {
+		return 0
+	}
+	return utils.HaversineKm(a.Lat, a.Long, b.Lat, b.Long)
+}
+
+// ---------------------------------- A* Search -------------------------
+
+// routeState is a station reached on a line. Tracking the line lets the search
+// count interchanges and prefer fewer of them between routes of equal length.
+type routeState struct {
+	stationID int
+	lineID    string
+}
+
+type routeStep struct {
+	state      routeState
+	distanceKm float64
+}
+
+type routeCost struct {
+	distanceKm float64
+	changes    int
+}
+
+func (c routeCost) less(other routeCost) bool {
+	const epsilon = 1e-9
+	if math.Abs(c.distanceKm-other.distanceKm) > epsilon {
+		return c.distanceKm < other.distanceKm
+	}
+	return c.changes < other.changes
+}
+
+// shortestPath runs A* with the great circle distance to the destination as
+// the heuristic. It returns nil when the stations are not connected.
+func (g *routeGraph) shortestPath(from, to int) []routeStep {
+	start := routeState{stationID: from}
+
+	costs := map[routeState]routeCost{start: {}}
+	previous := make(map[routeState]routeStep)
+	done := make(map[routeState]bool)
+
+	queue := &routeQueue{}
+	heap.Push(queue, &routeQueueItem{state: start, priority: routeCost{distanceKm: g.straightLineKm(from, to)}})
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(*routeQueueItem)
+		state := item.state
+		if done[state] {
+			continue
+		}
+		done[state] = true
+
+		if state.stationID == to {
+			return g.buildPath(start, state, previous, costs)
+		}
+
+		cost := costs[state]
+		for _, edge := range g.adjacency[state.stationID] {
+			next := routeState{stationID: edge.to, lineID: edge.lineID}
+			if done[next] {
+				continue
+			}
+
+			nextCost := routeCost{distanceKm: cost.distanceKm + edge.distanceKm, changes: cost.changes}
+			if state.lineID != "" && state.lineID != edge.lineID {
+				nextCost.changes++
+			}
+
+			if known, ok := costs[next]; ok && !nextCost.less(known) {
+				continue
+			}
+
+			costs[next] = nextCost
+			previous[next] = routeStep{state: state, distanceKm: edge.distanceKm}
+			heap.Push(queue, &routeQueueItem{
+				state: next,
+				priority: routeCost{
+					distanceKm: nextCost.distanceKm + g.straightLineKm(edge.to, to),
+					changes:    nextCost.changes,
+				},
+			})
+		}
+	}
+
+	return nil
+}
+
+func (g *routeGraph) buildPath(start, end routeState, previous map[routeState]routeStep, costs map[routeState]routeCost) []routeStep {
+	var path []routeStep
+	for state := end; state != start; state = previous[state].state {
+		path = append(path, routeStep{state: state, distanceKm: costs[state].distanceKm})
+	}
+	path = append(path, routeStep{state: start})
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
+
+type routeQueueItem struct {
+	state    routeState
+	priority routeCost
+}
+
+type routeQueue []*routeQueueItem
+
+func (q routeQueue) Len() int            { return len(q) }
+func (q routeQueue) Less(i, j int) bool  { return q[i].priority.less(q[j].priority) }
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(*routeQueueItem)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,40 @@
+package line
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type LineStationModel struct {
+	StationID int     `bson:"station_id" json:"station_id"`
+	Chainage  float64 `bson:"chainage_km" json:"chainage_km"`
+}
+
+type LineModel struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LineID    string             `bson:"line_id" json:"line_id"`
+	Name      string             `bson:"name" json:"name"`
+	EnName    string             `bson:"en_name" json:"en_name"`
+	Color     string             `bson:"color" json:"color"`
+	Stations  []LineStationModel `bson:"stations" json:"stations"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// StationPointModel is the part of a station the route planner needs.
+type StationPointModel struct {
+	StationID int     `bson:"id" json:"station_id"`
+	Name      string  `bson:"name" json:"name"`
+	EnName    string  `bson:"en_name" json:"en_name"`
+	Lat       float64 `bson:"lat" json:"lat"`
+	Long      float64 `bson:"long" json:"long"`
+	// ExactDistance is the station's position along the track in metres and
+	// KM its whole km post; 0 when the station data leaves them out
+	ExactDistance int `bson:"exact_distance" json:"-"`
+	KM            int `bson:"km" json:"-"`
+}
+
+// lengthKm is the distance between the first and last kilometre posts.
+func (l *LineModel) lengthKm() float64 {
+	if len(l.Stations) < 2 {
+		return 0
+	}
+	return l.Stations[len(l.Stations)-1].Chainage - l.Stations[0].Chainage
+}
@@ -0,0 +1,258 @@
+package line
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrLineNotFound = errors.New("line not found")
+	ErrLineExists   = errors.New("line already exists")
+)
+
+type LineRepository interface {
+	FindAll(ctx context.Context) ([]LineModel, error)
+	FindByID(ctx context.Context, lineID string) (*LineModel, error)
+	FindByStation(ctx context.Context, stationID int) ([]LineModel, error)
+	FindStations(ctx context.Context, lineID string) ([]LineStationData, error)
+	FindStationKMs(ctx context.Context, stationIDs []int) (map[int]int, error)
+	FindStationPoints(ctx context.Context, stationIDs []int) ([]StationPointModel, error)
+	Insert(ctx context.Context, line *LineModel) error
+	Update(ctx context.Context, line *LineModel) error
+	Delete(ctx context.Context, lineID string) error
+}
+
+type lineRepositoryType struct {
+	collection *mongo.Collection
+	stations   *mongo.Collection
+}
+
+func NewLineRepository(collection *mongo.Collection, stations *mongo.Collection) LineRepository {
+	return &lineRepositoryType{
+		collection: collection,
+		stations:   stations,
+	}
+}
+
+// ---------------------------------- Find All -------------------------
+func (r *lineRepositoryType) FindAll(ctx context.Context) ([]LineModel, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"line_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lines: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var lines []LineModel
+	if err := cursor.All(ctx, &lines); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return lines, nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *lineRepositoryType) FindByID(ctx context.Context, lineID string) (*LineModel, error) {
+	var line LineModel
+
+	err := r.collection.FindOne(ctx, bson.M{"line_id": lineID}).Decode(&line)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrLineNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find line: %w", err)
+	}
+
+	return &line, nil
+}
+
+// ---------------------------------- Find By Station -------------------------
+func (r *lineRepositoryType) FindByStation(ctx context.Context, stationID int) ([]LineModel, error) {
+	filter := bson.M{"stations.station_id": stationID}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"line_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lines: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var lines []LineModel
+	if err := cursor.All(ctx, &lines); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return lines, nil
+}
+
+// ---------------------------------- Find Stations -------------------------
+
+// FindStations returns the stations of a line in line order joined with their
+// station details. Stations that no longer exist keep their place with empty
+// details so the sequence is not broken.
+func (r *lineRepositoryType) FindStations(ctx context.Context, lineID string) ([]LineStationData, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"line_id": lineID}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":              "$stations",
+			"includeArrayIndex": "sequence",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         r.stations.Name(),
+			"localField":   "stations.station_id",
+			"foreignField": "id",
+			"as":           "station",
+		}}},
+		{{Key: "$unwind", Value: bson.M{
+			"path":                       "$station",
+			"preserveNullAndEmptyArrays": true,
+		}}},
+		{{Key: "$sort", Value: bson.M{"sequence": 1}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":         0,
+			"sequence":    bson.M{"$add": bson.A{"$sequence", 1}},
+			"station_id":  "$stations.station_id",
+			"chainage_km": "$stations.chainage_km",
+			"name":        "$station.name",
+			"en_name":     "$station.en_name",
+			"lat":         "$station.lat",
+			"long":        "$station.long",
+			"active":      "$station.active",
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find line stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stations []LineStationData
+	if err := cursor.All(ctx, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Find Station KMs -------------------------
+
+// FindStationKMs returns the km post of each existing, non-deleted station.
+func (r *lineRepositoryType) FindStationKMs(ctx context.Context, stationIDs []int) (map[int]int, error) {
+	filter := bson.M{
+		"id":         bson.M{"$in": stationIDs},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	projection := bson.M{"_id": 0, "id": 1, "km": 1}
+
+	cursor, err := r.stations.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		StationID int `bson:"id"`
+		KM        int `bson:"km"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	kms := make(map[int]int, len(results))
+	for _, result := range results {
+		kms[result.StationID] = result.KM
+	}
+
+	return kms, nil
+}
+
+// ---------------------------------- Find Station Points -------------------------
+func (r *lineRepositoryType) FindStationPoints(ctx context.Context, stationIDs []int) ([]StationPointModel, error) {
+	filter := bson.M{
+		"id":         bson.M{"$in": stationIDs},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	projection := bson.M{"_id": 0, "id": 1, "name": 1, "en_name": 1, "lat": 1, "long": 1, "exact_distance": 1, "km": 1}
+
+	cursor, err := r.stations.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stations []StationPointModel
+	if err := cursor.All(ctx, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *lineRepositoryType) Insert(ctx context.Context, line *LineModel) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	line.ID = primitive.NilObjectID
+	line.CreatedAt = now
+	line.UpdatedAt = now
+
+	result, err := r.collection.InsertOne(ctx, line)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLineExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert line: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		line.ID = id
+	}
+
+	return nil
+}
+
+// ---------------------------------- Update -------------------------
+func (r *lineRepositoryType) Update(ctx context.Context, line *LineModel) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":       line.Name,
+			"en_name":    line.EnName,
+			"color":      line.Color,
+			"stations":   line.Stations,
+			"updated_at": now,
+		},
+	}
+
+	var updated LineModel
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"line_id": line.LineID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrLineNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update line: %w", err)
+	}
+
+	*line = updated
+	return nil
+}
+
+// ---------------------------------- Delete -------------------------
+func (r *lineRepositoryType) Delete(ctx context.Context, lineID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"line_id": lineID})
+	if err != nil {
+		return fmt.Errorf("failed to delete line: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrLineNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package line
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrStationNotOnNetwork = errors.New("station is not on any line")
+	ErrNoRoute             = errors.New("no route between stations")
+)
+
+// routeGraphTTL bounds how stale the graph can get after station imports.
+// Line changes made through this service rebuild it straight away.
+const routeGraphTTL = 5 * time.Minute
+
+// ---------------------------------- Find Route -------------------------
+func (s *lineServiceType) FindRoute(ctx context.Context, req RouteRequest) (*RouteResponse, error) {
+	if req.From < 1 || req.To < 1 {
+		return nil, fmt.Errorf("%w: from and to must be station ids greater than 0", ErrInvalidLine)
+	}
+
+	graph, err := s.routeGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stationID := range []int{req.From, req.To} {
+		if !graph.hasStation(stationID) {
+			return nil, fmt.Errorf("%w: %d", ErrStationNotOnNetwork, stationID)
+		}
+	}
+
+	path := graph.shortestPath(req.From, req.To)
+	if path == nil {
+		return nil, fmt.Errorf("%w: %d and %d", ErrNoRoute, req.From, req.To)
+	}
+
+	// The start station is boarded on the line of the first edge
+	if len(path) > 1 {
+		path[0].state.lineID = path[1].state.lineID
+	}
+
+	response := &RouteResponse{
+		Success:      true,
+		From:         req.From,
+		To:           req.To,
+		DistanceKm:   path[len(path)-1].distanceKm,
+		Stations:     make([]RouteStationData, 0, len(path)),
+		Legs:         []RouteLegData{},
+		Interchanges: []RouteInterchange{},
+	}
+
+	for i, step := range path {
+		point := graph.stations[step.state.stationID]
+
+		response.Stations = append(response.Stations, RouteStationData{
+			Sequence:   i + 1,
+			StationID:  point.StationID,
+			Name:       point.Name,
+			EnName:     point.EnName,
+			Lat:        point.Lat,
+			Long:       point.Long,
+			LineID:     step.state.lineID,
+			DistanceKm: step.distanceKm,
+		})
+
+		if i == 0 {
+			continue
+		}
+
+		previous := path[i-1]
+		legs := response.Legs
+		if len(legs) == 0 || legs[len(legs)-1].LineID != step.state.lineID {
+			if len(legs) > 0 {
+				response.Interchanges = append(response.Interchanges, RouteInterchange{
+					StationID:  previous.state.stationID,
+					Name:       graph.stations[previous.state.stationID].Name,
+					EnName:     graph.stations[previous.state.stationID].EnName,
+					FromLineID: legs[len(legs)-1].LineID,
+					ToLineID:   step.state.lineID,
+				})
+			}
+
+			line := graph.lines[step.state.lineID]
+			response.Legs = append(response.Legs, RouteLegData{
+				LineID:        line.LineID,
+				Name:          line.Name,
+				EnName:        line.EnName,
+				FromStationID: previous.state.stationID,
+				StationCount:  1,
+			})
+		}
+
+		leg := &response.Legs[len(response.Legs)-1]
+		leg.ToStationID = step.state.stationID
+		leg.StationCount++
+		leg.DistanceKm += step.distanceKm - previous.distanceKm
+	}
+
+	return response, nil
+}
+
+// routeGraph returns the cached graph, rebuilding it once it is older than
+// routeGraphTTL.
+func (s *lineServiceType) routeGraph(ctx context.Context) (*routeGraph, error) {
+	s.graphMu.Lock()
+	defer s.graphMu.Unlock()
+
+	if s.graph != nil && time.Since(s.graphBuiltAt) < routeGraphTTL {
+		return s.graph, nil
+	}
+
+	lines, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	var stationIDs []int
+	for _, line := range lines {
+		for _, station := range line.Stations {
+			if !seen[station.StationID] {
+				seen[station.StationID] = true
+				stationIDs = append(stationIDs, station.StationID)
+			}
+		}
+	}
+
+	var points []StationPointModel
+	if len(stationIDs) > 0 {
+		points, err = s.repo.FindStationPoints(ctx, stationIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.graph = newRouteGraph(lines, points)
+	s.graphBuiltAt = time.Now()
+	return s.graph, nil
+}
+
+func (s *lineServiceType) invalidateRouteGraph() {
+	s.graphMu.Lock()
+	defer s.graphMu.Unlock()
+
+	s.graph = nil
+}
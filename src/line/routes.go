@@ -0,0 +1,25 @@
+package line
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func LineRoutes(api fiber.Router, DB *mongo.Database) {
+	lineRepo := NewLineRepository(DB.Collection("lines"), DB.Collection("stations"))
+
+	lineService := NewLineService(lineRepo)
+	lineController := NewLineController(lineService)
+
+	lineGroup := api.Group("/line")
+
+	lineGroup.Get("/", lineController.GetLines)
+	lineGroup.Post("/", lineController.PostLine)
+	lineGroup.Get("/:lineId", lineController.GetLine)
+	lineGroup.Get("/:lineId/stations", lineController.GetLineStations)
+	lineGroup.Put("/:lineId", lineController.PutLine)
+	lineGroup.Delete("/:lineId", lineController.DeleteLine)
+
+	api.Get("/station/:id<int>/lines", lineController.GetStationLines)
+	api.Get("/route", lineController.GetRoute)
+}
@@ -0,0 +1,257 @@
+package line
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLine = errors.New("invalid line")
+
+type LineService interface {
+	ListLines(ctx context.Context) (*LineListResponse, error)
+	GetLine(ctx context.Context, lineID string) (*LineResponse, error)
+	GetLineStations(ctx context.Context, lineID string) (*LineStationsResponse, error)
+	GetStationLines(ctx context.Context, stationID int) (*StationLinesResponse, error)
+	CreateLine(ctx context.Context, req LineRequest) (*LineResponse, error)
+	ReplaceLine(ctx context.Context, lineID string, req LineRequest) (*LineResponse, error)
+	DeleteLine(ctx context.Context, lineID string) (*LineDeleteResponse, error)
+	FindRoute(ctx context.Context, req RouteRequest) (*RouteResponse, error)
+}
+
+type lineServiceType struct {
+	repo         LineRepository
+	graphMu      sync.Mutex
+	graph        *routeGraph
+	graphBuiltAt time.Time
+}
+
+func NewLineService(repo LineRepository) LineService {
+	return &lineServiceType{
+		repo: repo,
+	}
+}
+
+// ---------------------------------- List Lines -------------------------
+func (s *lineServiceType) ListLines(ctx context.Context) (*LineListResponse, error) {
+	lines, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]LineSummaryData, 0, len(lines))
+	for _, line := range lines {
+		data = append(data, LineSummaryData{
+			LineID:       line.LineID,
+			Name:         line.Name,
+			EnName:       line.EnName,
+			Color:        line.Color,
+			StationCount: len(line.Stations),
+			LengthKm:     line.lengthKm(),
+		})
+	}
+
+	return &LineListResponse{
+		Success: true,
+		Data:    data,
+	}, nil
+}
+
+// ---------------------------------- Get Line -------------------------
+func (s *lineServiceType) GetLine(ctx context.Context, lineID string) (*LineResponse, error) {
+	line, err := s.repo.FindByID(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LineResponse{
+		Success: true,
+		Data:    line,
+	}, nil
+}
+
+// ---------------------------------- Get Line Stations -------------------------
+func (s *lineServiceType) GetLineStations(ctx context.Context, lineID string) (*LineStationsResponse, error) {
+	line, err := s.repo.FindByID(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+
+	stations, err := s.repo.FindStations(ctx, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if stations == nil {
+		stations = []LineStationData{}
+	}
+
+	return &LineStationsResponse{
+		Success: true,
+		LineID:  line.LineID,
+		Name:    line.Name,
+		EnName:  line.EnName,
+		Data:    stations,
+	}, nil
+}
+
+// ---------------------------------- Get Station Lines -------------------------
+func (s *lineServiceType) GetStationLines(ctx context.Context, stationID int) (*StationLinesResponse, error) {
+	lines, err := s.repo.FindByStation(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]StationLineData, 0, len(lines))
+	for _, line := range lines {
+		for i, station := range line.Stations {
+			if station.StationID != stationID {
+				continue
+			}
+
+			data = append(data, StationLineData{
+				LineID:   line.LineID,
+				Name:     line.Name,
+				EnName:   line.EnName,
+				Color:    line.Color,
+				Sequence: i + 1,
+				Chainage: station.Chainage,
+			})
+			break
+		}
+	}
+
+	return &StationLinesResponse{
+		Success:   true,
+		StationID: stationID,
+		Data:      data,
+	}, nil
+}
+
+// ---------------------------------- Create Line -------------------------
+func (s *lineServiceType) CreateLine(ctx context.Context, req LineRequest) (*LineResponse, error) {
+	line, err := s.buildLine(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Insert(ctx, line); err != nil {
+		return nil, err
+	}
+	s.invalidateRouteGraph()
+
+	return &LineResponse{
+		Success: true,
+		Data:    line,
+	}, nil
+}
+
+// ---------------------------------- Replace Line -------------------------
+func (s *lineServiceType) ReplaceLine(ctx context.Context, lineID string, req LineRequest) (*LineResponse, error) {
+	if req.LineID != "" && strings.TrimSpace(req.LineID) != lineID {
+		return nil, fmt.Errorf("%w: line_id in body does not match line_id in path", ErrInvalidLine)
+	}
+	req.LineID = lineID
+
+	line, err := s.buildLine(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, line); err != nil {
+		return nil, err
+	}
+	s.invalidateRouteGraph()
+
+	return &LineResponse{
+		Success: true,
+		Data:    line,
+	}, nil
+}
+
+// ---------------------------------- Delete Line -------------------------
+func (s *lineServiceType) DeleteLine(ctx context.Context, lineID string) (*LineDeleteResponse, error) {
+	if err := s.repo.Delete(ctx, lineID); err != nil {
+		return nil, err
+	}
+	s.invalidateRouteGraph()
+
+	return &LineDeleteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Line %s deleted", lineID),
+	}, nil
+}
+
+// buildLine validates the request and fills any missing chainage from the
+// station's km post. Stations must exist and their chainage must not decrease
+// along the line.
+func (s *lineServiceType) buildLine(ctx context.Context, req LineRequest) (*LineModel, error) {
+	lineID := strings.TrimSpace(req.LineID)
+	if lineID == "" {
+		return nil, fmt.Errorf("%w: line_id is required", ErrInvalidLine)
+	}
+	if strings.TrimSpace(req.Name) == "" && strings.TrimSpace(req.EnName) == "" {
+		return nil, fmt.Errorf("%w: name or en_name is required", ErrInvalidLine)
+	}
+	if len(req.Stations) < 2 {
+		return nil, fmt.Errorf("%w: a line needs at least 2 stations", ErrInvalidLine)
+	}
+
+	stationIDs := make([]int, 0, len(req.Stations))
+	seen := make(map[int]bool, len(req.Stations))
+	for i, station := range req.Stations {
+		if station.StationID < 1 {
+			return nil, fmt.Errorf("%w: stations[%d]: station_id must be greater than 0", ErrInvalidLine, i)
+		}
+		if seen[station.StationID] {
+			return nil, fmt.Errorf("%w: stations[%d]: station %d is listed more than once", ErrInvalidLine, i, station.StationID)
+		}
+		seen[station.StationID] = true
+		stationIDs = append(stationIDs, station.StationID)
+	}
+
+	kms, err := s.repo.FindStationKMs(ctx, stationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, stationID := range stationIDs {
+		if _, ok := kms[stationID]; !ok {
+			missing = append(missing, fmt.Sprint(stationID))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: unknown stations: %s", ErrInvalidLine, strings.Join(missing, ", "))
+	}
+
+	stations := make([]LineStationModel, 0, len(req.Stations))
+	for i, station := range req.Stations {
+		chainage := float64(kms[station.StationID])
+		if station.Chainage != nil {
+			chainage = *station.Chainage
+		}
+
+		if chainage < 0 {
+			return nil, fmt.Errorf("%w: stations[%d]: chainage_km must not be negative", ErrInvalidLine, i)
+		}
+		if i > 0 && chainage < stations[i-1].Chainage {
+			return nil, fmt.Errorf("%w: stations[%d]: chainage_km %v is less than the previous station's %v", ErrInvalidLine, i, chainage, stations[i-1].Chainage)
+		}
+
+		stations = append(stations, LineStationModel{
+			StationID: station.StationID,
+			Chainage:  chainage,
+		})
+	}
+
+	return &LineModel{
+		LineID:   lineID,
+		Name:     strings.TrimSpace(req.Name),
+		EnName:   strings.TrimSpace(req.EnName),
+		Color:    strings.TrimSpace(req.Color),
+		Stations: stations,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const commandUsage = `usage: migrate <command> [flags]
+
+commands:
+  up [-to version]    apply pending migrations, up to version when given
+  down [-steps n]     roll back the newest applied migrations (default 1)
+  status              list migrations and when they were applied
+`
+
+// RunCommand runs the migrate subcommand, args excludes "migrate" itself.
+func RunCommand(ctx context.Context, db *mongo.Database, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, commandUsage)
+		return fmt.Errorf("missing migrate command")
+	}
+
+	migrator := NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		flags.SetOutput(out)
+		target := flags.Int("to", 0, "last version to apply, 0 applies all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		done, err := migrator.Up(ctx, *target)
+		printMigrations(out, "applied", done)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return nil
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		flags.SetOutput(out)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+
+		done, err := migrator.Down(ctx, *steps)
+		printMigrations(out, "rolled back", done)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "no applied migrations")
+		}
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		fmt.Fprint(out, commandUsage)
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func printMigrations(out io.Writer, verb string, migrations []Migration) {
+	for _, migration := range migrations {
+		fmt.Fprintf(out, "%s %04d %s\n", verb, migration.Version, migration.Name)
+	}
+}
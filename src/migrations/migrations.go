@@ -0,0 +1,308 @@
+// Package migrations applies numbered MongoDB schema changes, such as index
+// creation and data backfills, and records them in schema_migrations.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName     = "schema_migrations"
+	lockCollectionName = "schema_migrations_lock"
+	lockID             = "migrations"
+	// lockTimeout frees the lock of an instance that died mid-migration
+	lockTimeout      = 10 * time.Minute
+	lockPollInterval = time.Second
+)
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Migration is one schema change. Up and Down must be safe to run again after
+// a partial failure, because a migration is only recorded once Up returns.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+type AppliedMigrationModel struct {
+	Version   int                `bson:"version" json:"version"`
+	Name      string             `bson:"name" json:"name"`
+	AppliedAt primitive.DateTime `bson:"applied_at" json:"applied_at"`
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	locks      *mongo.Collection
+	migrations []Migration
+}
+
+// NewMigrator uses the migrations in versions.go.
+func NewMigrator(db *mongo.Database) *Migrator {
+	return newMigrator(db, All())
+}
+
+func newMigrator(db *mongo.Database, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		collection: db.Collection(collectionName),
+		locks:      db.Collection(lockCollectionName),
+		migrations: sorted,
+	}
+}
+
+// ---------------------------------- Status -------------------------
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if record, ok := applied[migration.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = record.AppliedAt.Time()
+		}
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations Up would apply.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// ---------------------------------- Up -------------------------
+
+// Up applies pending migrations in order up to and including target. A target
+// of 0 applies all of them. Instances starting together wait for the lock, so
+// only the first applies each migration.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	if target != 0 && !m.known(target) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		if target != 0 && migration.Version > target {
+			break
+		}
+
+		if err := migration.Up(ctx, m.db); err != nil {
+			return done, fmt.Errorf("migration %04d %s failed: %w", migration.Version, migration.Name, err)
+		}
+
+		record := AppliedMigrationModel{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: primitive.NewDateTimeFromTime(time.Now()),
+		}
+		// A duplicate means another run recorded it, which still counts as applied
+		if _, err := m.collection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return done, fmt.Errorf("failed to record migration %04d: %w", migration.Version, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// ---------------------------------- Down -------------------------
+
+// Down rolls back the given number of applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if migration.Down != nil {
+			if err := migration.Down(ctx, m.db); err != nil {
+				return done, fmt.Errorf("rollback of %04d %s failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		if _, err := m.collection.DeleteOne(ctx, bson.M{"version": migration.Version}); err != nil {
+			return done, fmt.Errorf("failed to remove migration record %04d: %w", migration.Version, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// ---------------------------------- Lock -------------------------
+
+// lock waits until it holds the single lock document, or the holder's lock
+// has timed out, and returns the function that releases it.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	owner := primitive.NewObjectID()
+
+	for {
+		now := time.Now()
+		filter := bson.M{
+			"_id":        lockID,
+			"expires_at": bson.M{"$lt": primitive.NewDateTimeFromTime(now)},
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"owner":      owner,
+				"expires_at": primitive.NewDateTimeFromTime(now.Add(lockTimeout)),
+			},
+		}
+
+		// The upsert only inserts when no lock exists; a held lock makes it
+		// collide on _id
+		_, err := m.locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to lock schema_migrations: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for the schema_migrations lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		m.locks.DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	}
+	return unlock, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]AppliedMigrationModel, error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetName("version_unique").SetUnique(true),
+	}
+	if _, err := m.collection.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations index: %w", err)
+	}
+
+	cursor, err := m.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []AppliedMigrationModel
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]AppliedMigrationModel, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// ---------------------------------- Helpers -------------------------
+
+// createIndexes is idempotent: MongoDB accepts an index that already exists
+// with the same name and keys.
+func createIndexes(ctx context.Context, collection *mongo.Collection, indexes ...mongo.IndexModel) error {
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create %s indexes: %w", collection.Name(), err)
+	}
+	return nil
+}
+
+// dropIndexes ignores indexes and collections that are already gone.
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := collection.Indexes().DropOne(ctx, name)
+
+		var commandErr mongo.CommandError
+		if errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27) {
+			continue // NamespaceNotFound, IndexNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to drop %s index %s: %w", collection.Name(), name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All returns every migration in version order. Append new migrations with the
+// next version number, never edit one that has shipped.
+func All() []Migration {
+	return []Migration{
+		{Version: 1, Name: "station_location_index", Up: upStationLocationIndex, Down: downStationLocationIndex},
+		{Version: 2, Name: "import_job_indexes", Up: upImportJobIndexes, Down: downImportJobIndexes},
+		{Version: 3, Name: "station_history_indexes", Up: upStationHistoryIndexes, Down: downStationHistoryIndexes},
+		{Version: 4, Name: "line_indexes", Up: upLineIndexes, Down: downLineIndexes},
+		{Version: 5, Name: "timetable_indexes", Up: upTimetableIndexes, Down: downTimetableIndexes},
+		{Version: 6, Name: "station_id_unique", Up: upStationIDUnique, Down: downStationIDUnique},
+		{Version: 7, Name: "station_history_counters", Up: upStationHistoryCounters, Down: downStationHistoryCounters},
+	}
+}
+
+// ---------------------------------- 0001 station_location_index -------------------------
+func upStationLocationIndex(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db.Collection("stations"), mongo.IndexModel{
+		Keys:    bson.D{{Key: "location", Value: "2dsphere"}},
+		Options: options.Index().SetName("location_2dsphere"),
+	})
+}
+
+func downStationLocationIndex(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db.Collection("stations"), "location_2dsphere")
+}
+
+// ---------------------------------- 0002 import_job_indexes -------------------------
+func upImportJobIndexes(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(ctx, db.Collection("import_jobs"), mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+		Options: options.Index().SetName("status_created_at"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, db.Collection("import_job_issues"), mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "job_id", Value: 1},
+			{Key: "index", Value: 1},
+		},
+		Options: options.Index().SetName("job_id_index"),
+	})
+}
+
+func downImportJobIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := dropIndexes(ctx, db.Collection("import_job_issues"), "job_id_index"); err != nil {
+		return err
+	}
+	return dropIndexes(ctx, db.Collection("import_jobs"), "status_created_at")
+}
+
+// ---------------------------------- 0003 station_history_indexes -------------------------
+func upStationHistoryIndexes(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db.Collection("station_history"),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "station_id", Value: 1},
+				{Key: "version", Value: -1},
+			},
+			Options: options.Index().SetName("station_id_version").SetUnique(true),
+		},
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "station_id", Value: 1},
+				{Key: "changed_at", Value: -1},
+			},
+			Options: options.Index().SetName("station_id_changed_at"),
+		},
+	)
+}
+
+func downStationHistoryIndexes(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db.Collection("station_history"), "station_id_version", "station_id_changed_at")
+}
+
+// ---------------------------------- 0004 line_indexes -------------------------
+func upLineIndexes(ctx context.Context, db *mongo.Database) error {
+	return createIndexes(ctx, db.Collection("lines"),
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "line_id", Value: 1}},
+			Options: options.Index().SetName("line_id_unique").SetUnique(true),
+		},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "stations.station_id", Value: 1}},
+			Options: options.Index().SetName("stations_station_id"),
+		},
+	)
+}
+
+func downLineIndexes(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db.Collection("lines"), "line_id_unique", "stations_station_id")
+}
+
+// ---------------------------------- 0005 timetable_indexes -------------------------
+func upTimetableIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := []struct {
+		collection string
+		models     []mongo.IndexModel
+	}{
+		{"gtfs_stop_times", []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "station_id", Value: 1},
+					{Key: "service_id", Value: 1},
+					{Key: "departure_secs", Value: 1},
+				},
+				Options: options.Index().SetName("station_service_departure"),
+			},
+			{
+				Keys:    bson.D{{Key: "trip_id", Value: 1}, {Key: "stop_sequence", Value: 1}},
+				Options: options.Index().SetName("trip_sequence"),
+			},
+		}},
+		{"gtfs_trips", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "trip_id", Value: 1}}, Options: options.Index().SetName("trip_id")},
+		}},
+		{"gtfs_routes", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "route_id", Value: 1}}, Options: options.Index().SetName("route_id")},
+		}},
+		{"gtfs_calendar_dates", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "date", Value: 1}}, Options: options.Index().SetName("date")},
+		}},
+		{"gtfs_stops", []mongo.IndexModel{
+			{Keys: bson.D{{Key: "stop_id", Value: 1}}, Options: options.Index().SetName("stop_id")},
+		}},
+	}
+
+	for _, index := range indexes {
+		if err := createIndexes(ctx, db.Collection(index.collection), index.models...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func downTimetableIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]string{
+		"gtfs_stop_times":     {"station_service_departure", "trip_sequence"},
+		"gtfs_trips":          {"trip_id"},
+		"gtfs_routes":         {"route_id"},
+		"gtfs_calendar_dates": {"date"},
+		"gtfs_stops":          {"stop_id"},
+	}
+
+	for collection, names := range indexes {
+		if err := dropIndexes(ctx, db.Collection(collection), names...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ---------------------------------- 0006 station_id_unique -------------------------
+func upStationIDUnique(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(ctx, db.Collection("stations"), mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetName("id_unique").SetUnique(true),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("stations has duplicate ids, remove the duplicates before applying this migration: %w", err)
+	}
+	return err
+}
+
+func downStationIDUnique(ctx context.Context, db *mongo.Database) error {
+	return dropIndexes(ctx, db.Collection("stations"), "id_unique")
+}
+
+// ---------------------------------- 0007 station_history_counters -------------------------
+
+// upStationHistoryCounters seeds the per-station version counters from the
+// history already written, keeping any counter that is further ahead.
+func upStationHistoryCounters(ctx context.Context, db *mongo.Database) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$station_id",
+			"version": bson.M{"$max": "$version"},
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into": "station_history_counters",
+			"on":   "_id",
+			"whenMatched": bson.A{
+				bson.M{"$set": bson.M{"version": bson.M{"$max": bson.A{"$version", "$$new.version"}}}},
+			},
+			"whenNotMatched": "insert",
+		}}},
+	}
+
+	cursor, err := db.Collection("station_history").Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to seed station_history_counters: %w", err)
+	}
+	return cursor.Close(ctx)
+}
+
+func downStationHistoryCounters(ctx context.Context, db *mongo.Database) error {
+	return db.Collection("station_history_counters").Drop(ctx)
+}
@@ -0,0 +1,231 @@
+// Package mvt encodes point layers as Mapbox Vector Tiles (spec v2.1).
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultExtent = 4096
+	layerVersion  = 2
+	geomTypePoint = 1
+	cmdMoveTo     = 1
+)
+
+// Feature is a point in tile coordinates, 0..Extent on both axes with y
+// growing downwards.
+type Feature struct {
+	ID         uint64
+	X, Y       int
+	Properties map[string]interface{}
+}
+
+type Layer struct {
+	Name     string
+	Extent   int
+	Features []Feature
+}
+
+// ---------------------------------- Projection -------------------------
+
+// TileCoordinates projects a point to pixel coordinates of tile z/x/y.
+// Points outside the tile give values outside 0..extent.
+func TileCoordinates(lat, long float64, z, x, y, extent int) (int, int) {
+	const maxLat = 85.05112878
+	lat = math.Max(-maxLat, math.Min(maxLat, lat))
+
+	n := math.Exp2(float64(z))
+	mx := (long + 180) / 360 * n
+	sin := math.Sin(lat * math.Pi / 180)
+	my := (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * n
+
+	return int(math.Floor((mx - float64(x)) * float64(extent))), int(math.Floor((my - float64(y)) * float64(extent)))
+}
+
+// TileBounds returns minLon,minLat,maxLon,maxLat of tile z/x/y.
+func TileBounds(z, x, y int) [4]float64 {
+	n := math.Exp2(float64(z))
+	lon := func(x float64) float64 { return x/n*360 - 180 }
+	lat := func(y float64) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+	}
+
+	return [4]float64{lon(float64(x)), lat(float64(y + 1)), lon(float64(x + 1)), lat(float64(y))}
+}
+
+// ---------------------------------- Encode -------------------------
+func Encode(layers ...Layer) ([]byte, error) {
+	var tile buffer
+	for _, layer := range layers {
+		encoded, err := encodeLayer(layer)
+		if err != nil {
+			return nil, err
+		}
+		tile.bytesField(3, encoded)
+	}
+	return tile.data, nil
+}
+
+func encodeLayer(layer Layer) ([]byte, error) {
+	extent := layer.Extent
+	if extent == 0 {
+		extent = DefaultExtent
+	}
+
+	var keys []string
+	keyIndex := make(map[string]int)
+	var values [][]byte
+	valueIndex := make(map[string]int)
+
+	var out buffer
+	out.varintField(15, layerVersion)
+	out.stringField(1, layer.Name)
+
+	for _, feature := range layer.Features {
+		names := make([]string, 0, len(feature.Properties))
+		for name := range feature.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		tags := make([]uint64, 0, len(names)*2)
+		for _, name := range names {
+			value, err := encodeValue(feature.Properties[name])
+			if err != nil {
+				return nil, fmt.Errorf("feature %d property %q: %w", feature.ID, name, err)
+			}
+			if value == nil {
+				continue
+			}
+
+			k, ok := keyIndex[name]
+			if !ok {
+				k = len(keys)
+				keyIndex[name] = k
+				keys = append(keys, name)
+			}
+
+			v, ok := valueIndex[string(value)]
+			if !ok {
+				v = len(values)
+				valueIndex[string(value)] = v
+				values = append(values, value)
+			}
+
+			tags = append(tags, uint64(k), uint64(v))
+		}
+
+		var f buffer
+		f.varintField(1, feature.ID)
+		if len(tags) > 0 {
+			f.packedField(2, tags)
+		}
+		f.varintField(3, geomTypePoint)
+		f.packedField(4, []uint64{
+			commandInteger(cmdMoveTo, 1),
+			zigzag(feature.X),
+			zigzag(feature.Y),
+		})
+
+		out.bytesField(2, f.data)
+	}
+
+	for _, key := range keys {
+		out.stringField(3, key)
+	}
+	for _, value := range values {
+		out.bytesField(4, value)
+	}
+	out.varintField(5, uint64(extent))
+
+	return out.data, nil
+}
+
+// encodeValue returns nil for nil and empty string values, which are left out
+// of the feature.
+func encodeValue(value interface{}) ([]byte, error) {
+	var v buffer
+
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if value == "" {
+			return nil, nil
+		}
+		v.stringField(1, value)
+	case float64:
+		v.fixed64Field(3, math.Float64bits(value))
+	case int:
+		v.varintField(6, zigzag(value))
+	case int64:
+		v.varintField(6, zigzag(int(value)))
+	case bool:
+		flag := uint64(0)
+		if value {
+			flag = 1
+		}
+		v.varintField(7, flag)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+
+	return v.data, nil
+}
+
+func commandInteger(id, count int) uint64 {
+	return uint64(id&0x7) | uint64(count)<<3
+}
+
+func zigzag(n int) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+// ---------------------------------- Protobuf -------------------------
+type buffer struct {
+	data []byte
+}
+
+func (b *buffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.data = append(b.data, byte(v)|0x80)
+		v >>= 7
+	}
+	b.data = append(b.data, byte(v))
+}
+
+func (b *buffer) key(field, wireType int) {
+	b.varint(uint64(field<<3 | wireType))
+}
+
+func (b *buffer) varintField(field int, v uint64) {
+	b.key(field, 0)
+	b.varint(v)
+}
+
+func (b *buffer) fixed64Field(field int, v uint64) {
+	b.key(field, 1)
+	for i := 0; i < 8; i++ {
+		b.data = append(b.data, byte(v>>(8*i)))
+	}
+}
+
+func (b *buffer) bytesField(field int, v []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *buffer) stringField(field int, v string) {
+	b.bytesField(field, []byte(v))
+}
+
+func (b *buffer) packedField(field int, values []uint64) {
+	var packed buffer
+	for _, v := range values {
+		packed.varint(v)
+	}
+	b.bytesField(field, packed.data)
+}
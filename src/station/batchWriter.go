@@ -0,0 +1,356 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/zombox0633/go_spinsoft/src/georules"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultImportBatchSize = 500
+	maxImportBatchSize     = 5000
+	reportPreviewLimit     = 100
+)
+
+const (
+	MissingPolicyIgnore     = "ignore"
+	MissingPolicyDeactivate = "deactivate"
+	MissingPolicySoftDelete = "soft_delete"
+)
+
+func (o StationImportOptions) normalize() (StationImportOptions, error) {
+	if o.BatchSize == 0 {
+		o.BatchSize = defaultImportBatchSize
+	}
+
+	if o.BatchSize < 1 || o.BatchSize > maxImportBatchSize {
+		return o, fmt.Errorf("%w: invalid batch_size: must be between 1 and %d", ErrInvalidStation, maxImportBatchSize)
+	}
+
+	switch o.MissingPolicy {
+	case "":
+		o.MissingPolicy = MissingPolicyIgnore
+	case MissingPolicyIgnore, MissingPolicyDeactivate, MissingPolicySoftDelete:
+	default:
+		return o, fmt.Errorf("%w: invalid missing_policy: must be one of ignore, deactivate, soft_delete", ErrInvalidStation)
+	}
+
+	return o, nil
+}
+
+// stationBatchWriter buffers parsed stations and upserts them every batchSize
+// records, so an import only ever holds one batch in memory.
+type stationBatchWriter struct {
+	ctx         context.Context
+	repo        StationRepository
+	historyRepo StationHistoryRepository
+	reportRepo  ImportReportRepository
+	geoRules    *georules.Engine
+	mapCache    *stationMapCache
+	jobID       *primitive.ObjectID
+	opts        StationImportOptions
+	buffer      []StationModel
+	issues      []StationImportIssue
+	seen        map[int]struct{}
+	result      StationImportResponse
+	onProgress  func(StationImportResponse)
+}
+
+func (s *stationServiceType) newStationBatchWriter(ctx context.Context, opts StationImportOptions, onProgress func(StationImportResponse)) *stationBatchWriter {
+	return &stationBatchWriter{
+		ctx:         ctx,
+		repo:        s.repo,
+		historyRepo: s.historyRepo,
+		reportRepo:  s.reportRepo,
+		geoRules:    s.geoRules,
+		mapCache:    s.mapCache,
+		jobID:       stationChangeFrom(ctx).importJobID,
+		opts:        opts,
+		buffer:      make([]StationModel, 0, opts.BatchSize),
+		seen:        make(map[int]struct{}),
+		result: StationImportResponse{
+			Batches:           []StationImportBatch{},
+			MissingPolicy:     opts.MissingPolicy,
+			MissingStationIDs: []int{},
+			Report:            []StationImportIssue{},
+		},
+		onProgress: onProgress,
+	}
+}
+
+func (w *stationBatchWriter) add(station StationModel) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	station.applyGeoRules(w.geoRules)
+
+	index := w.result.TotalCount
+	w.result.TotalCount++
+
+	for _, issue := range station.Issues {
+		issue.Index = index
+		w.addIssue(issue)
+	}
+
+	// A rejected row still shows the station is in the feed, so it must not
+	// be reconciled as missing
+	if station.StationID > 0 {
+		w.seen[station.StationID] = struct{}{}
+	}
+
+	if station.IsRejected() {
+		w.result.RejectedCount++
+		return nil
+	}
+
+	if station.WasInvalidated {
+		w.result.InvalidCoordinates++
+	}
+
+	w.buffer = append(w.buffer, station)
+	if len(w.buffer) >= w.opts.BatchSize {
+		w.flush()
+	}
+
+	return nil
+}
+
+func (w *stationBatchWriter) flush() {
+	if len(w.buffer) == 0 {
+		return
+	}
+
+	batch := StationImportBatch{
+		Batch: len(w.result.Batches) + 1,
+		Count: len(w.buffer),
+	}
+
+	for _, station := range w.buffer {
+		if station.WasInvalidated {
+			batch.InvalidCoordinates++
+		}
+	}
+
+	// Without the stored versions the batch would change stations without
+	// history, so it is not written
+	before, err := w.storedStations()
+	if err != nil {
+		batch.Error = fmt.Sprintf("failed to load stored stations: %v", err)
+	} else if upserted, err := w.repo.UpsertMany(w.ctx, w.buffer); err != nil {
+		batch.Error = err.Error()
+	} else {
+		batch.Inserted = upserted.Inserted
+		batch.Updated = upserted.Updated
+		w.result.ImportedCount += batch.Count
+
+		if err := w.recordHistory(before); err != nil {
+			batch.Error = fmt.Sprintf("stations saved but history not recorded: %v", err)
+		} else {
+			batch.Success = true
+		}
+	}
+
+	w.result.ProcessedCount += batch.Count
+	w.result.Batches = append(w.result.Batches, batch)
+	w.buffer = w.buffer[:0]
+	w.flushIssues()
+
+	if w.onProgress != nil {
+		w.result.Success = true
+		w.result.Message = fmt.Sprintf("Processed %d stations in %d batches", w.result.ProcessedCount, len(w.result.Batches))
+		w.onProgress(w.result)
+	}
+}
+
+func (w *stationBatchWriter) storedStations() (map[int]StationModel, error) {
+	stationIDs := make([]int, len(w.buffer))
+	for i, station := range w.buffer {
+		stationIDs[i] = station.StationID
+	}
+
+	stored, err := w.repo.FindByIDs(w.ctx, stationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	storedByID := make(map[int]StationModel, len(stored))
+	for _, station := range stored {
+		storedByID[station.StationID] = station
+	}
+
+	return storedByID, nil
+}
+
+func (w *stationBatchWriter) recordHistory(before map[int]StationModel) error {
+	changes := make([]stationVersionChange, len(w.buffer))
+	for i, station := range w.buffer {
+		changes[i] = stationVersionChange{after: station}
+		if old, ok := before[station.StationID]; ok {
+			changes[i].before = &old
+		}
+	}
+
+	return recordHistory(w.ctx, w.historyRepo, StationChangeImport, changes)
+}
+
+func (w *stationBatchWriter) addIssue(issue StationImportIssue) {
+	w.result.ReportTotal++
+	if len(w.result.Report) < reportPreviewLimit {
+		w.result.Report = append(w.result.Report, issue)
+	} else {
+		w.result.ReportTruncated = true
+	}
+
+	if w.jobID != nil {
+		issue.JobID = w.jobID
+		w.issues = append(w.issues, issue)
+	}
+}
+
+// flushIssues keeps the full report of a job import outside the job document.
+func (w *stationBatchWriter) flushIssues() {
+	if len(w.issues) == 0 {
+		return
+	}
+
+	if err := w.reportRepo.InsertMany(w.ctx, w.issues); err != nil {
+		log.Printf("Warning: Failed to store import report: %v", err)
+	}
+	w.issues = w.issues[:0]
+}
+
+func (w *stationBatchWriter) finish(streamErr error) *StationImportResponse {
+	if streamErr != nil && w.ctx.Err() == nil {
+		w.addIssue(StationImportIssue{
+			Index:  w.result.TotalCount,
+			Field:  "record",
+			Reason: streamErr.Error(),
+			Action: IssueActionRejected,
+		})
+	}
+
+	w.flush()
+	w.flushIssues()
+	defer w.mapCache.invalidate()
+
+	failedBatches := 0
+	for _, batch := range w.result.Batches {
+		if !batch.Success {
+			failedBatches++
+		}
+	}
+
+	switch {
+	case streamErr != nil:
+		w.result.Success = false
+		w.result.Message = fmt.Sprintf("Import stopped after %d stations: %v", w.result.TotalCount, streamErr)
+	case failedBatches > 0:
+		w.result.Success = false
+		w.result.Message = fmt.Sprintf("%d of %d batches failed", failedBatches, len(w.result.Batches))
+	default:
+		w.result.Success = true
+		w.result.Message = "Import completed successfully"
+
+		// Only a complete feed can tell which stations are missing
+		if err := w.reconcileMissing(); err != nil {
+			w.result.Success = false
+			w.result.Message = fmt.Sprintf("Import completed but reconciling missing stations failed: %v", err)
+		}
+	}
+
+	return &w.result
+}
+
+func (w *stationBatchWriter) reconcileMissing() error {
+	if w.opts.MissingPolicy == MissingPolicyIgnore || len(w.seen) == 0 {
+		return nil
+	}
+
+	storedIDs, err := w.repo.FindAllIDs(w.ctx)
+	if err != nil {
+		return err
+	}
+
+	var missingIDs []int
+	for _, stationID := range storedIDs {
+		if _, ok := w.seen[stationID]; !ok {
+			missingIDs = append(missingIDs, stationID)
+		}
+	}
+
+	if len(missingIDs) == 0 {
+		return nil
+	}
+
+	before, err := w.repo.FindByIDs(w.ctx, missingIDs)
+	if err != nil {
+		return err
+	}
+
+	// Stations deactivated by an earlier import stay as they are
+	if w.opts.MissingPolicy == MissingPolicyDeactivate {
+		active := before[:0]
+		missingIDs = missingIDs[:0]
+		for _, station := range before {
+			if station.Active != 0 {
+				active = append(active, station)
+				missingIDs = append(missingIDs, station.StationID)
+			}
+		}
+		before = active
+
+		if len(missingIDs) == 0 {
+			return nil
+		}
+	}
+
+	switch w.opts.MissingPolicy {
+	case MissingPolicyDeactivate:
+		comment := w.opts.MissingComment
+		if comment == "" {
+			comment = "Missing from import feed"
+		}
+		err = w.repo.DeactivateMany(w.ctx, missingIDs, comment)
+	case MissingPolicySoftDelete:
+		err = w.repo.SoftDeleteMany(w.ctx, missingIDs)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.result.MissingStationIDs = missingIDs
+
+	after, err := w.repo.FindByIDs(w.ctx, missingIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load reconciled stations for history: %w", err)
+	}
+
+	beforeByID := make(map[int]StationModel, len(before))
+	for _, station := range before {
+		beforeByID[station.StationID] = station
+	}
+
+	changes := make([]stationVersionChange, 0, len(after))
+	for _, station := range after {
+		change := stationVersionChange{after: station}
+		if old, ok := beforeByID[station.StationID]; ok {
+			change.before = &old
+		}
+		changes = append(changes, change)
+	}
+
+	changeType := StationChangeImport
+	if w.opts.MissingPolicy == MissingPolicySoftDelete {
+		changeType = StationChangeDelete
+	}
+
+	if err := recordHistory(w.ctx, w.historyRepo, changeType, changes); err != nil {
+		return fmt.Errorf("history not recorded: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	maxClusterZoom  = 22
+	clusterMaxZoom  = 13
+	clusterCellSize = 64 // pixels at 256 px tiles
+)
+
+// stationMapCache holds the stations used by map endpoints, the clusters
+// built from the active ones per zoom level and encoded vector tiles. It is
+// cleared whenever stations change and rebuilt on the next read. mu only
+// guards the fields; loading and encoding happen outside it.
+type stationMapCache struct {
+	mu         sync.Mutex
+	generation int
+	points     []StationMapPoint
+	loaded     bool
+	loading    chan struct{} // closed when the load in flight finishes
+	clusters   map[int][]StationClusterData
+	tiles      map[tileKey][]byte
+}
+
+func newStationMapCache() *stationMapCache {
+	return &stationMapCache{
+		clusters: make(map[int][]StationClusterData),
+		tiles:    make(map[tileKey][]byte),
+	}
+}
+
+func (c *stationMapCache) invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.points = nil
+	c.loaded = false
+	c.loading = nil
+	c.clusters = make(map[int][]StationClusterData)
+	c.tiles = make(map[tileKey][]byte)
+}
+
+// load returns the cached points and the generation they belong to. Only one
+// caller queries the repository per generation; the others wait for it.
+func (c *stationMapCache) load(ctx context.Context, repo StationRepository) ([]StationMapPoint, int, error) {
+	for {
+		c.mu.Lock()
+		if c.loaded {
+			points, generation := c.points, c.generation
+			c.mu.Unlock()
+			return points, generation, nil
+		}
+
+		if wait := c.loading; wait != nil {
+			c.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			}
+		}
+
+		done := make(chan struct{})
+		c.loading = done
+		generation := c.generation
+		c.mu.Unlock()
+
+		points, err := repo.FindMapPoints(ctx)
+
+		c.mu.Lock()
+		if c.loading == done {
+			c.loading = nil
+		}
+		if err == nil && c.generation == generation {
+			c.points = points
+			c.loaded = true
+		}
+		c.mu.Unlock()
+		close(done)
+
+		if err != nil {
+			return nil, 0, err
+		}
+		return points, generation, nil
+	}
+}
+
+func (c *stationMapCache) clustersAt(ctx context.Context, repo StationRepository, zoom int) ([]StationClusterData, error) {
+	// Every zoom above clusterMaxZoom gives the same unclustered result
+	zoom = min(zoom, clusterMaxZoom+1)
+
+	c.mu.Lock()
+	clusters, ok := c.clusters[zoom]
+	c.mu.Unlock()
+	if ok {
+		return clusters, nil
+	}
+
+	points, generation, err := c.load(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters = buildClusters(activeMapPoints(points), zoom)
+
+	// Clusters built from points an update has since replaced are not kept
+	c.mu.Lock()
+	if c.generation == generation {
+		c.clusters[zoom] = clusters
+	}
+	c.mu.Unlock()
+
+	return clusters, nil
+}
+
+// activeMapPoints leaves out inactive stations, which tiles still show.
+func activeMapPoints(points []StationMapPoint) []StationMapPoint {
+	active := make([]StationMapPoint, 0, len(points))
+	for _, point := range points {
+		if point.Active == 1 {
+			active = append(active, point)
+		}
+	}
+	return active
+}
+
+// ---------------------------------- Grid Clustering -------------------------
+
+type clusterCell struct {
+	x, y int
+}
+
+// buildClusters groups stations into square Web Mercator cells of
+// clusterCellSize pixels. Above clusterMaxZoom every station stands alone.
+func buildClusters(points []StationMapPoint, zoom int) []StationClusterData {
+	if zoom > clusterMaxZoom {
+		clusters := make([]StationClusterData, 0, len(points))
+		for _, point := range points {
+			clusters = append(clusters, stationCluster(point))
+		}
+		return clusters
+	}
+
+	cells := math.Exp2(float64(zoom)) * 256 / clusterCellSize
+
+	members := make(map[clusterCell][]StationMapPoint)
+	var order []clusterCell
+	for _, point := range points {
+		x, y := mercator(point.Lat, point.Long)
+		cell := clusterCell{
+			x: int(math.Min(x*cells, cells-1)),
+			y: int(math.Min(y*cells, cells-1)),
+		}
+
+		if _, ok := members[cell]; !ok {
+			order = append(order, cell)
+		}
+		members[cell] = append(members[cell], point)
+	}
+
+	clusters := make([]StationClusterData, 0, len(order))
+	for _, cell := range order {
+		group := members[cell]
+		if len(group) == 1 {
+			clusters = append(clusters, stationCluster(group[0]))
+			continue
+		}
+
+		cluster := StationClusterData{
+			Cluster: true,
+			Count:   len(group),
+			Extent:  [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+		}
+		for _, point := range group {
+			cluster.Lat += point.Lat
+			cluster.Long += point.Long
+			cluster.Extent[0] = math.Min(cluster.Extent[0], point.Long)
+			cluster.Extent[1] = math.Min(cluster.Extent[1], point.Lat)
+			cluster.Extent[2] = math.Max(cluster.Extent[2], point.Long)
+			cluster.Extent[3] = math.Max(cluster.Extent[3], point.Lat)
+		}
+		cluster.Lat /= float64(len(group))
+		cluster.Long /= float64(len(group))
+
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters
+}
+
+func stationCluster(point StationMapPoint) StationClusterData {
+	return StationClusterData{
+		Count:  1,
+		Lat:    point.Lat,
+		Long:   point.Long,
+		Extent: [4]float64{point.Long, point.Lat, point.Long, point.Lat},
+		ID:     point.StationID,
+		Name:   point.Name,
+		EnName: point.EnName,
+	}
+}
+
+// mercator projects a point to Web Mercator with both axes in 0..1 and y
+// growing southwards, as in map tiles.
+func mercator(lat, long float64) (float64, float64) {
+	const maxLat = 85.05112878
+	lat = math.Max(-maxLat, math.Min(maxLat, lat))
+
+	x := (long + 180) / 360
+	sin := math.Sin(lat * math.Pi / 180)
+	y := 0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)
+
+	return x, y
+}
+
+// ---------------------------------- Find Station Clusters -------------------------
+func (s *stationServiceType) FindStationClusters(ctx context.Context, bbox [4]float64, zoom int) (*StationClusterResponse, error) {
+	if zoom < 0 || zoom > maxClusterZoom {
+		return nil, fmt.Errorf("%w: invalid zoom: must be between 0 and %d", ErrInvalidStation, maxClusterZoom)
+	}
+
+	clusters, err := s.mapCache.clustersAt(ctx, s.repo, zoom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build clusters: %w", err)
+	}
+
+	data := make([]StationClusterData, 0)
+	for _, cluster := range clusters {
+		if cluster.Long >= bbox[0] && cluster.Lat >= bbox[1] && cluster.Long <= bbox[2] && cluster.Lat <= bbox[3] {
+			data = append(data, cluster)
+		}
+	}
+
+	return &StationClusterResponse{
+		Success: true,
+		Zoom:    zoom,
+		Count:   len(data),
+		Data:    data,
+	}, nil
+}
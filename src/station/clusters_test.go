@@ -0,0 +1,100 @@
+package station
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingStationRepository counts the map point loads behind the cache.
+type countingStationRepository struct {
+	StationRepository
+	loads atomic.Int32
+}
+
+func (r *countingStationRepository) FindMapPoints(ctx context.Context) ([]StationMapPoint, error) {
+	r.loads.Add(1)
+	return r.StationRepository.FindMapPoints(ctx)
+}
+
+func newMapTestService(t *testing.T) (StationService, *countingStationRepository) {
+	t.Helper()
+
+	memory, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+	repo := &countingStationRepository{StationRepository: memory}
+	return NewStationService(repo, nil, &fakeHistoryRepository{}, nil, nil), repo
+}
+
+func TestStationMapPointsInactive(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newMapTestService(t)
+
+	// Station 1015 is inactive: tiles show it, clusters leave it out
+	points, err := repo.FindMapPoints(ctx)
+	if err != nil {
+		t.Fatalf("FindMapPoints: %v", err)
+	}
+	if !containsMapPoint(points, 1015) {
+		t.Errorf("map points leave out inactive station 1015")
+	}
+
+	response, err := service.FindStationClusters(ctx, [4]float64{-180, -90, 180, 90}, maxClusterZoom)
+	if err != nil {
+		t.Fatalf("FindStationClusters: %v", err)
+	}
+	for _, cluster := range response.Data {
+		if cluster.ID == 1015 {
+			t.Errorf("clusters include inactive station 1015")
+		}
+	}
+}
+
+func TestStationMapCacheLoadsOncePerGeneration(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newMapTestService(t)
+
+	read := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := service.StationTile(ctx, 1, i%2, i/2%2); err != nil {
+					t.Errorf("StationTile: %v", err)
+				}
+				if _, err := service.FindStationClusters(ctx, [4]float64{-180, -90, 180, 90}, i); err != nil {
+					t.Errorf("FindStationClusters: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	read()
+	if loads := repo.loads.Load(); loads != 1 {
+		t.Fatalf("got %d loads, want 1", loads)
+	}
+
+	// A write starts a new generation, which is loaded once more
+	comment := "reloaded"
+	if _, err := service.PatchStation(ctx, 1001, StationPatchRequest{Comment: &comment}); err != nil {
+		t.Fatalf("PatchStation: %v", err)
+	}
+	read()
+	if loads := repo.loads.Load(); loads != 2 {
+		t.Fatalf("got %d loads after a write, want 2", loads)
+	}
+}
+
+func containsMapPoint(points []StationMapPoint, stationID int) bool {
+	for _, point := range points {
+		if point.StationID == stationID {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,18 @@
 package station
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type StationControllerType struct {
@@ -24,14 +33,84 @@ func (c *StationControllerType) PostImportStationsURL(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	result, err := c.service.ImportFromURL(ctx.Context(), req.URL)
+	if req.DryRun {
+		diff, err := c.service.DiffFromURL(ctx.Context(), req.URL)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(diff)
+	}
+
+	result, err := c.service.CreateImportJob(ctx.Context(), req)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(result)
+}
+
+// ---------------------------------- PostImportStationsFile -------------------------
+func (c *StationControllerType) PostImportStationsFile(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required file: file")
+	}
+
+	var req StationFileImportRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if mapping := ctx.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid mapping: must be a JSON object of field to column")
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Failed to open uploaded file")
+	}
+	defer file.Close()
+
+	stations, err := c.service.ParseStationFile(fileHeader.Filename, file, req)
+	if err != nil {
+		return stationError(err)
+	}
+
+	if req.DryRun {
+		diff, err := c.service.DiffStations(ctx.Context(), stations)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(diff)
+	}
+
+	result, err := c.service.ImportStations(changeContext(ctx), stations, req.StationImportOptions)
+	if err != nil {
+		return stationError(err)
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(result)
 }
 
+// ---------------------------------- Get Import Job -------------------------
+func (c *StationControllerType) GetImportJob(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid import job id")
+	}
+
+	result, err := c.service.GetImportJob(ctx.Context(), id)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
 // ---------------------------------- Get Nearest Station -------------------------
 func (c *StationControllerType) GetNearestStation(ctx *fiber.Ctx) error {
 	latStr := ctx.Query("lat")
@@ -115,3 +194,383 @@ func (c *StationControllerType) GetNearestStationPagination(ctx *fiber.Ctx) erro
 
 	return ctx.Status(fiber.StatusOK).JSON(result)
 }
+
+// ---------------------------------- Search Stations -------------------------
+func (c *StationControllerType) GetSearchStations(ctx *fiber.Ctx) error {
+	query := ctx.Query("q")
+	latStr := ctx.Query("lat")
+	longStr := ctx.Query("long")
+	limitStr := ctx.Query("limit", "10")
+
+	if query == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameter: q")
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	req := StationSearchRequest{
+		Query: query,
+		Limit: limit,
+	}
+
+	if latStr != "" || longStr != "" {
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid latitude")
+		}
+
+		long, err := strconv.ParseFloat(longStr, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid longitude")
+		}
+
+		req.Lat = &lat
+		req.Long = &long
+	}
+
+	result, err := c.service.SearchStations(ctx.Context(), req)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Station -------------------------
+func (c *StationControllerType) GetStation(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	if asOfStr := ctx.Query("as_of"); asOfStr != "" {
+		asOf, err := parseAsOf(asOfStr)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid as_of: must be RFC3339 or YYYY-MM-DD")
+		}
+
+		result, err := c.service.GetStationAsOf(ctx.Context(), stationID, asOf)
+		if err != nil {
+			return stationError(err)
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(result)
+	}
+
+	result, err := c.service.GetStation(ctx.Context(), stationID)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Station History -------------------------
+func (c *StationControllerType) GetStationHistory(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "50"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	result, err := c.service.GetStationHistory(ctx.Context(), stationID, limit)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Post Station -------------------------
+func (c *StationControllerType) PostStation(ctx *fiber.Ctx) error {
+	var station StationModel
+
+	if err := ctx.BodyParser(&station); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.service.CreateStation(changeContext(ctx), station)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(result)
+}
+
+// ---------------------------------- Put Station -------------------------
+func (c *StationControllerType) PutStation(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	var station StationModel
+	if err := ctx.BodyParser(&station); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.service.ReplaceStation(changeContext(ctx), stationID, station)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Patch Station -------------------------
+func (c *StationControllerType) PatchStation(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	var patch StationPatchRequest
+	if err := ctx.BodyParser(&patch); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.service.PatchStation(changeContext(ctx), stationID, patch)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Delete Station -------------------------
+func (c *StationControllerType) DeleteStation(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	result, err := c.service.DeleteStation(changeContext(ctx), stationID)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// changeContext tags writes made through the API with the caller from the
+// X-Changed-By header so they show up in the station history.
+func changeContext(ctx *fiber.Ctx) context.Context {
+	actor := ctx.Get("X-Changed-By", "api")
+	return withStationChange(ctx.Context(), actor, nil)
+}
+
+func parseAsOf(value string) (time.Time, error) {
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return asOf, nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// A bare date means the end of that day
+	return date.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// ---------------------------------- Get Import Report -------------------------
+func (c *StationControllerType) GetImportReport(ctx *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid import job id")
+	}
+
+	var buffer bytes.Buffer
+	if err := c.service.WriteImportReportCSV(ctx.Context(), id, &buffer); err != nil {
+		return stationError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	ctx.Attachment(fmt.Sprintf("import-report-%s.csv", id.Hex()))
+	return ctx.Status(fiber.StatusOK).Send(buffer.Bytes())
+}
+
+// ---------------------------------- Get Stations Within -------------------------
+func (c *StationControllerType) GetStationsWithin(ctx *fiber.Ctx) error {
+	bboxStr := ctx.Query("bbox")
+	if bboxStr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameter: bbox")
+	}
+
+	bbox, err := ParseBBox(bboxStr)
+	if err != nil {
+		return stationError(err)
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "500"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	result, err := c.service.FindStationsInBBox(ctx.Context(), bbox, limit)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Post Stations Within -------------------------
+func (c *StationControllerType) PostStationsWithin(ctx *fiber.Ctx) error {
+	var geometry GeoJSONGeometry
+
+	if err := json.Unmarshal(ctx.Body(), &geometry); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body: must be a GeoJSON Polygon or MultiPolygon")
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "500"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	result, err := c.service.FindStationsWithin(ctx.Context(), geometry, limit)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Station Clusters -------------------------
+func (c *StationControllerType) GetStationClusters(ctx *fiber.Ctx) error {
+	bboxStr := ctx.Query("bbox")
+	zoomStr := ctx.Query("zoom")
+
+	if bboxStr == "" || zoomStr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameters: bbox and zoom")
+	}
+
+	bbox, err := ParseBBox(bboxStr)
+	if err != nil {
+		return stationError(err)
+	}
+
+	zoom, err := strconv.Atoi(zoomStr)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid zoom")
+	}
+
+	result, err := c.service.FindStationClusters(ctx.Context(), bbox, zoom)
+	if err != nil {
+		return stationError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Station Tile -------------------------
+func (c *StationControllerType) GetStationTile(ctx *fiber.Ctx) error {
+	z, err := ctx.ParamsInt("z")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid tile zoom")
+	}
+
+	x, err := ctx.ParamsInt("x")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid tile x")
+	}
+
+	y, err := ctx.ParamsInt("y")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid tile y")
+	}
+
+	tile, err := c.service.StationTile(ctx.Context(), z, x, y)
+	if err != nil {
+		return stationError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/vnd.mapbox-vector-tile")
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=60")
+	return ctx.Status(fiber.StatusOK).Send(tile)
+}
+
+// ---------------------------------- Get Station Export -------------------------
+func (c *StationControllerType) GetStationExport(ctx *fiber.Ctx) error {
+	// An explicit format wins over the Accept header
+	format := ctx.Query("format")
+	if format == "" {
+		format = exportFormatForMediaType(ctx.Accepts(exportMediaTypes()...))
+	}
+
+	req := StationExportRequest{
+		Format: format,
+	}
+
+	latStr := ctx.Query("lat")
+	longStr := ctx.Query("long")
+
+	if latStr != "" {
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid latitude")
+		}
+		req.Lat = &lat
+	}
+
+	if longStr != "" {
+		long, err := strconv.ParseFloat(longStr, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid longitude")
+		}
+		req.Long = &long
+	}
+
+	if radiusStr := ctx.Query("radius_km"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid radius_km")
+		}
+		req.RadiusKm = radius
+	}
+
+	if err := req.validate(); err != nil {
+		return stationError(err)
+	}
+
+	exportFormat, err := lookupExportFormat(req.Format)
+	if err != nil {
+		return stationError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, exportFormat.ContentType)
+	ctx.Set(fiber.HeaderVary, fiber.HeaderAccept)
+	ctx.Attachment("stations." + exportFormat.Extension)
+	ctx.Status(fiber.StatusOK)
+
+	// The handler returns before the body is written, so the export cannot
+	// use the request context
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := c.service.ExportStations(context.Background(), req, w); err != nil {
+			log.Printf("Station export failed: %v", err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+func stationError(err error) error {
+	switch {
+	case errors.Is(err, ErrStationNotFound), errors.Is(err, ErrImportJobNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrStationExists):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidStation), errors.Is(err, ErrUnsupportedFormat), errors.Is(err, ErrUnsupportedExportFormat):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,139 @@
+package station
+
+import (
+	"context"
+	"fmt"
+)
+
+var stationDiffFields = []string{
+	"station_code",
+	"name",
+	"en_name",
+	"th_short",
+	"en_short",
+	"chname",
+	"controldivision",
+	"exact_km",
+	"exact_distance",
+	"km",
+	"class",
+	"lat",
+	"long",
+	"active",
+	"giveway",
+	"dual_track",
+	"comment",
+}
+
+// ---------------------------------- Diff From URL -------------------------
+func (s *stationServiceType) DiffFromURL(ctx context.Context, url string) (*StationImportDiffResponse, error) {
+	stations, err := s.fetchStations(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.DiffStations(ctx, stations)
+}
+
+// ---------------------------------- Diff Stations -------------------------
+func (s *stationServiceType) DiffStations(ctx context.Context, stations []StationModel) (*StationImportDiffResponse, error) {
+	stored, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored stations: %w", err)
+	}
+
+	for i := range stations {
+		stations[i].applyGeoRules(s.geoRules)
+	}
+
+	return diffStations(stored, stations), nil
+}
+
+func diffStations(stored, incoming []StationModel) *StationImportDiffResponse {
+	response := &StationImportDiffResponse{
+		Success:          true,
+		DryRun:           true,
+		TotalCount:       len(incoming),
+		NewStations:      []StationDiffSummary{},
+		ChangedStations:  []StationDiffChange{},
+		MissingStations:  []StationDiffSummary{},
+		NewlyInvalidated: []StationDiffSummary{},
+	}
+
+	storedByID := make(map[int]StationModel, len(stored))
+	for _, station := range stored {
+		storedByID[station.StationID] = station
+	}
+
+	seen := make(map[int]bool, len(incoming))
+	for _, station := range incoming {
+		if station.IsRejected() || seen[station.StationID] {
+			continue
+		}
+		seen[station.StationID] = true
+
+		old, exists := storedByID[station.StationID]
+		if !exists {
+			response.NewStations = append(response.NewStations, diffSummary(station))
+			continue
+		}
+
+		if station.WasInvalidated && old.Location != nil {
+			summary := diffSummary(station)
+			summary.Reason = station.InvalidReason
+			response.NewlyInvalidated = append(response.NewlyInvalidated, summary)
+		}
+
+		changes := diffStationFields(old, station)
+		if len(changes) == 0 {
+			response.Summary.Unchanged++
+			continue
+		}
+
+		response.ChangedStations = append(response.ChangedStations, StationDiffChange{
+			ID:      station.StationID,
+			Name:    station.Name,
+			EnName:  station.EnName,
+			Changes: changes,
+		})
+	}
+
+	for _, station := range stored {
+		if !seen[station.StationID] && station.DeletedAt == nil {
+			response.MissingStations = append(response.MissingStations, diffSummary(station))
+		}
+	}
+
+	response.Summary.New = len(response.NewStations)
+	response.Summary.Changed = len(response.ChangedStations)
+	response.Summary.Missing = len(response.MissingStations)
+	response.Summary.NewlyInvalidated = len(response.NewlyInvalidated)
+
+	return response
+}
+
+func diffStationFields(old, station StationModel) []StationFieldDiff {
+	oldFields := stationSetFields(old, 0)
+	newFields := stationSetFields(station, 0)
+
+	var changes []StationFieldDiff
+	for _, field := range stationDiffFields {
+		if oldFields[field] != newFields[field] {
+			changes = append(changes, StationFieldDiff{
+				Field: field,
+				Old:   oldFields[field],
+				New:   newFields[field],
+			})
+		}
+	}
+
+	return changes
+}
+
+func diffSummary(station StationModel) StationDiffSummary {
+	return StationDiffSummary{
+		ID:     station.StationID,
+		Name:   station.Name,
+		EnName: station.EnName,
+	}
+}
@@ -2,14 +2,101 @@ package station
 
 // Station Import
 type StationImportRequest struct {
-	URL string `json:"url" validate:"required,url"`
+	URL                  string `bson:"url" json:"url" validate:"required,url"`
+	DryRun               bool   `bson:"dry_run" json:"dry_run"`
+	StationImportOptions `bson:",inline"`
+}
+
+type StationImportOptions struct {
+	BatchSize      int    `bson:"batch_size" json:"batch_size,omitempty" form:"batch_size"`
+	MissingPolicy  string `bson:"missing_policy" json:"missing_policy,omitempty" form:"missing_policy"`
+	MissingComment string `bson:"missing_comment" json:"missing_comment,omitempty" form:"missing_comment"`
 }
 
 type StationImportResponse struct {
-	Success            bool   `json:"success"`
-	ImportedCount      int    `json:"imported_count"`
-	InvalidCoordinates int    `json:"invalid_coordinates"`
-	Message            string `json:"message"`
+	Success            bool                 `json:"success"`
+	TotalCount         int                  `json:"total_count"`
+	ProcessedCount     int                  `json:"processed_count"`
+	ImportedCount      int                  `json:"imported_count"`
+	InvalidCoordinates int                  `json:"invalid_coordinates"`
+	Message            string               `json:"message"`
+	Batches            []StationImportBatch `json:"batches"`
+	MissingPolicy      string               `json:"missing_policy"`
+	MissingStationIDs  []int                `json:"missing_station_ids"`
+	RejectedCount      int                  `json:"rejected_count"`
+	Report             []StationImportIssue `json:"report"`
+	ReportTotal        int                  `json:"report_total"`
+	ReportTruncated    bool                 `json:"report_truncated"`
+}
+
+type StationImportBatch struct {
+	Batch              int    `bson:"batch" json:"batch"`
+	Count              int    `bson:"count" json:"count"`
+	Inserted           int    `bson:"inserted" json:"inserted"`
+	Updated            int    `bson:"updated" json:"updated"`
+	InvalidCoordinates int    `bson:"invalid_coordinates" json:"invalid_coordinates"`
+	Success            bool   `bson:"success" json:"success"`
+	Error              string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+type StationUpsertResult struct {
+	Inserted int
+	Updated  int
+}
+
+// Import From File
+type StationFileImportRequest struct {
+	Format    string `form:"format"`
+	Delimiter string `form:"delimiter"`
+	DryRun    bool   `form:"dry_run"`
+	StationImportOptions
+	Mapping map[string]string `form:"-"`
+}
+
+// Import Dry Run
+type StationImportDiffResponse struct {
+	Success          bool                    `json:"success"`
+	DryRun           bool                    `json:"dry_run"`
+	TotalCount       int                     `json:"total_count"`
+	Summary          StationImportDiffCounts `json:"summary"`
+	NewStations      []StationDiffSummary    `json:"new_stations"`
+	ChangedStations  []StationDiffChange     `json:"changed_stations"`
+	MissingStations  []StationDiffSummary    `json:"missing_stations"`
+	NewlyInvalidated []StationDiffSummary    `json:"newly_invalidated"`
+}
+
+type StationImportDiffCounts struct {
+	New              int `json:"new"`
+	Changed          int `json:"changed"`
+	Unchanged        int `json:"unchanged"`
+	Missing          int `json:"missing"`
+	NewlyInvalidated int `json:"newly_invalidated"`
+}
+
+type StationDiffSummary struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	EnName string `json:"en_name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type StationDiffChange struct {
+	ID      int                `json:"id"`
+	Name    string             `json:"name"`
+	EnName  string             `json:"en_name"`
+	Changes []StationFieldDiff `json:"changes"`
+}
+
+type StationFieldDiff struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Import Job
+type ImportJobResponse struct {
+	Success bool            `json:"success"`
+	Data    *ImportJobModel `json:"data"`
 }
 
 // Find Near Station
@@ -52,3 +139,151 @@ type NearestStationPaginationResponse struct {
 	TotalItems int                  `json:"total_items"`
 	Data       []NearestStationData `json:"data"`
 }
+
+// Export
+type StationExportRequest struct {
+	Format   string
+	Lat      *float64
+	Long     *float64
+	RadiusKm float64
+}
+
+// Within
+type StationWithinRequest struct {
+	shape *geoWithinShape
+	Limit int
+}
+
+type StationWithinResponse struct {
+	Success   bool                `json:"success"`
+	Count     int                 `json:"count"`
+	Truncated bool                `json:"truncated"`
+	Data      []StationWithinData `json:"data"`
+}
+
+type StationWithinData struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	EnName string  `json:"en_name"`
+	Lat    float64 `json:"lat"`
+	Long   float64 `json:"long"`
+}
+
+// Clusters
+type StationMapPoint struct {
+	StationID int     `bson:"id" json:"id"`
+	Name      string  `bson:"name" json:"name"`
+	EnName    string  `bson:"en_name" json:"en_name"`
+	Lat       float64 `bson:"lat" json:"lat"`
+	Long      float64 `bson:"long" json:"long"`
+	Class     int     `bson:"class" json:"class"`
+	Active    int     `bson:"active" json:"active"`
+	DualTrack int     `bson:"dual_track" json:"dual_track"`
+
+	Location *GeoJSONPointModel `bson:"location" json:"-"`
+}
+
+type StationClusterResponse struct {
+	Success bool                 `json:"success"`
+	Zoom    int                  `json:"zoom"`
+	Count   int                  `json:"count"`
+	Data    []StationClusterData `json:"data"`
+}
+
+// StationClusterData is a cluster when Cluster is true, otherwise a single
+// station. Extent is minLon,minLat,maxLon,maxLat of the members.
+type StationClusterData struct {
+	Cluster bool       `json:"cluster"`
+	Count   int        `json:"count"`
+	Lat     float64    `json:"lat"`
+	Long    float64    `json:"long"`
+	Extent  [4]float64 `json:"extent"`
+	ID      int        `json:"id,omitempty"`
+	Name    string     `json:"name,omitempty"`
+	EnName  string     `json:"en_name,omitempty"`
+}
+
+// Station CRUD
+type StationResponse struct {
+	Success bool          `json:"success"`
+	Data    *StationModel `json:"data"`
+}
+
+type StationPatchRequest struct {
+	StationCode   *int     `json:"station_code,omitempty"`
+	Name          *string  `json:"name,omitempty"`
+	EnName        *string  `json:"en_name,omitempty"`
+	ThShort       *string  `json:"th_short,omitempty"`
+	EnShort       *string  `json:"en_short,omitempty"`
+	ChName        *string  `json:"chname,omitempty"`
+	ControlDiv    *int     `json:"controldivision,omitempty"`
+	ExactKM       *int     `json:"exact_km,omitempty"`
+	ExactDistance *int     `json:"exact_distance,omitempty"`
+	KM            *int     `json:"km,omitempty"`
+	Class         *int     `json:"class,omitempty"`
+	Lat           *float64 `json:"lat,omitempty"`
+	Long          *float64 `json:"long,omitempty"`
+	Active        *int     `json:"active,omitempty"`
+	Giveway       *int     `json:"giveway,omitempty"`
+	DualTrack     *int     `json:"dual_track,omitempty"`
+	Comment       *string  `json:"comment,omitempty"`
+}
+
+type StationDeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Station History
+type StationHistoryResponse struct {
+	Success   bool                  `json:"success"`
+	StationID int                   `json:"station_id"`
+	Data      []StationHistoryModel `json:"data"`
+}
+
+// Station Search
+type StationSearchRequest struct {
+	Query string   `json:"q" validate:"required"`
+	Lat   *float64 `json:"lat,omitempty"`
+	Long  *float64 `json:"long,omitempty"`
+	Limit int      `json:"limit,omitempty"`
+}
+
+type StationSearchResponse struct {
+	Success bool                `json:"success"`
+	Query   string              `json:"query"`
+	Data    []StationSearchData `json:"data"`
+}
+
+type StationSearchData struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	EnName       string   `json:"en_name"`
+	ThShort      string   `json:"th_short"`
+	EnShort      string   `json:"en_short"`
+	ChName       string   `json:"chname"`
+	Lat          float64  `json:"lat"`
+	Long         float64  `json:"long"`
+	MatchedField string   `json:"matched_field"`
+	Score        int      `json:"score"`
+	Distance     *float64 `json:"distance_km,omitempty"`
+}
+
+// Station Validation
+type StationValidationResponse struct {
+	Success            bool                 `json:"success"`
+	TotalCount         int                  `json:"total_count"`
+	ValidCount         int                  `json:"valid_count"`
+	InvalidCoordinates int                  `json:"invalid_coordinates"`
+	RejectedCount      int                  `json:"rejected_count"`
+	DuplicateIDs       []int                `json:"duplicate_ids"`
+	Report             []StationImportIssue `json:"report"`
+}
+
+// Station Reindex
+type StationReindexResponse struct {
+	Success      bool   `json:"success"`
+	TotalCount   int    `json:"total_count"`
+	UpdatedCount int    `json:"updated_count"`
+	Message      string `json:"message"`
+}
@@ -0,0 +1,62 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+const (
+	defaultExportRadiusKm = 10
+	maxExportRadiusKm     = 1000
+)
+
+// validate is called by the controller before the response starts, because a
+// streamed export can no longer change its status code.
+func (r *StationExportRequest) validate() error {
+	if _, err := lookupExportFormat(r.Format); err != nil {
+		return err
+	}
+
+	if (r.Lat == nil) != (r.Long == nil) {
+		return fmt.Errorf("%w: lat and long must be given together", ErrInvalidStation)
+	}
+
+	if r.Lat == nil {
+		return nil
+	}
+
+	if err := utils.ValidateCoordinates(*r.Lat, *r.Long); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStation, err)
+	}
+
+	if r.RadiusKm == 0 {
+		r.RadiusKm = defaultExportRadiusKm
+	}
+	if r.RadiusKm < 0 || r.RadiusKm > maxExportRadiusKm {
+		return fmt.Errorf("%w: invalid radius_km: must be between 0 and %d", ErrInvalidStation, maxExportRadiusKm)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Export Stations -------------------------
+func (s *stationServiceType) ExportStations(ctx context.Context, req StationExportRequest, w io.Writer) error {
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	exportFormat, err := lookupExportFormat(req.Format)
+	if err != nil {
+		return err
+	}
+
+	exporter := exportFormat.newExporter(w)
+	if err := s.repo.EachExportStation(ctx, req, exporter.WriteStation); err != nil {
+		return fmt.Errorf("failed to export stations: %w", err)
+	}
+
+	return exporter.Close()
+}
@@ -0,0 +1,347 @@
+package station
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StationExportGeoJSON = "geojson"
+	StationExportCSV     = "csv"
+	StationExportKML     = "kml"
+	StationExportGPX     = "gpx"
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// StationExporter writes stations one at a time so an export never holds the
+// whole dataset in memory. Close writes anything that has to follow the last
+// station.
+type StationExporter interface {
+	WriteStation(station StationModel) error
+	Close() error
+}
+
+type stationExportFormat struct {
+	Name        string
+	MediaType   string
+	ContentType string
+	Extension   string
+	newExporter func(w io.Writer) StationExporter
+}
+
+// stationExportFormats is in order of preference for Accept negotiation, so a
+// client accepting anything gets GeoJSON.
+var stationExportFormats = []stationExportFormat{
+	{
+		Name:        StationExportGeoJSON,
+		MediaType:   "application/geo+json",
+		ContentType: "application/geo+json",
+		Extension:   "geojson",
+		newExporter: newGeoJSONStationExporter,
+	},
+	{
+		Name:        StationExportCSV,
+		MediaType:   "text/csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		newExporter: newCSVStationExporter,
+	},
+	{
+		Name:        StationExportKML,
+		MediaType:   "application/vnd.google-earth.kml+xml",
+		ContentType: "application/vnd.google-earth.kml+xml; charset=utf-8",
+		Extension:   "kml",
+		newExporter: newKMLStationExporter,
+	},
+	{
+		Name:        StationExportGPX,
+		MediaType:   "application/gpx+xml",
+		ContentType: "application/gpx+xml; charset=utf-8",
+		Extension:   "gpx",
+		newExporter: newGPXStationExporter,
+	},
+}
+
+func lookupExportFormat(format string) (stationExportFormat, error) {
+	for _, exportFormat := range stationExportFormats {
+		if strings.EqualFold(exportFormat.Name, format) {
+			return exportFormat, nil
+		}
+	}
+	return stationExportFormat{}, fmt.Errorf("%w: %q", ErrUnsupportedExportFormat, format)
+}
+
+// exportMediaTypes lists the media types for Accept negotiation.
+func exportMediaTypes() []string {
+	mediaTypes := make([]string, len(stationExportFormats))
+	for i, exportFormat := range stationExportFormats {
+		mediaTypes[i] = exportFormat.MediaType
+	}
+	return mediaTypes
+}
+
+// exportFormatForMediaType returns the format name for a negotiated media
+// type, or GeoJSON when nothing matched.
+func exportFormatForMediaType(mediaType string) string {
+	for _, exportFormat := range stationExportFormats {
+		if exportFormat.MediaType == mediaType {
+			return exportFormat.Name
+		}
+	}
+	return StationExportGeoJSON
+}
+
+// ---------------------------------- GeoJSON -------------------------
+type geoJSONStationExporter struct {
+	w     io.Writer
+	count int
+}
+
+type geoJSONStationFeature struct {
+	Type       string             `json:"type"`
+	ID         int                `json:"id"`
+	Geometry   *GeoJSONPointModel `json:"geometry"`
+	Properties StationModel       `json:"properties"`
+}
+
+func newGeoJSONStationExporter(w io.Writer) StationExporter {
+	return &geoJSONStationExporter{w: w}
+}
+
+func (e *geoJSONStationExporter) WriteStation(station StationModel) error {
+	prefix := ",\n"
+	if e.count == 0 {
+		prefix = `{"type":"FeatureCollection","features":[` + "\n"
+	}
+
+	feature, err := json.Marshal(geoJSONStationFeature{
+		Type:       "Feature",
+		ID:         station.StationID,
+		Geometry:   station.Location,
+		Properties: station,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode station %d: %w", station.StationID, err)
+	}
+
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(feature); err != nil {
+		return err
+	}
+
+	e.count++
+	return nil
+}
+
+func (e *geoJSONStationExporter) Close() error {
+	closing := "\n]}\n"
+	if e.count == 0 {
+		closing = `{"type":"FeatureCollection","features":[]}` + "\n"
+	}
+
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+// ---------------------------------- CSV -------------------------
+
+// csvExportColumns match the CSV import columns so an export can be imported
+// again unchanged.
+var csvExportColumns = append(append([]string{}, stationFieldKeys...), "created_at", "updated_at")
+
+type csvStationExporter struct {
+	w       io.Writer
+	writer  *csv.Writer
+	started bool
+}
+
+func newCSVStationExporter(w io.Writer) StationExporter {
+	return &csvStationExporter{
+		w:      w,
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (e *csvStationExporter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	// BOM so spreadsheet tools read the Thai station names as UTF-8
+	if _, err := io.WriteString(e.w, "\ufeff"); err != nil {
+		return err
+	}
+	return e.writer.Write(csvExportColumns)
+}
+
+func (e *csvStationExporter) WriteStation(station StationModel) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	return e.writer.Write([]string{
+		strconv.Itoa(station.StationID),
+		strconv.Itoa(station.StationCode),
+		station.Name,
+		station.EnName,
+		station.ThShort,
+		station.EnShort,
+		station.ChName,
+		strconv.Itoa(station.ControlDiv),
+		strconv.Itoa(station.ExactKM),
+		strconv.Itoa(station.ExactDistance),
+		strconv.Itoa(station.KM),
+		strconv.Itoa(station.Class),
+		strconv.FormatFloat(station.Lat, 'f', -1, 64),
+		strconv.FormatFloat(station.Long, 'f', -1, 64),
+		strconv.Itoa(station.Active),
+		strconv.Itoa(station.Giveway),
+		strconv.Itoa(station.DualTrack),
+		station.Comment,
+		station.CreatedAt.Time().UTC().Format(time.RFC3339),
+		station.UpdatedAt.Time().UTC().Format(time.RFC3339),
+	})
+}
+
+func (e *csvStationExporter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ---------------------------------- KML -------------------------
+
+// kmlClassStyles colour placemarks by station class, as aabbggrr. Classes not
+// listed use the plain station style.
+var kmlClassStyles = []struct {
+	Class int
+	Color string
+	Scale float64
+}{
+	{1, "ff0000ff", 1.2},
+	{2, "ff00a5ff", 1.0},
+	{3, "ff00ff00", 0.8},
+}
+
+type kmlStationExporter struct {
+	w       io.Writer
+	started bool
+}
+
+func newKMLStationExporter(w io.Writer) StationExporter {
+	return &kmlStationExporter{w: w}
+}
+
+func (e *kmlStationExporter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	var header strings.Builder
+	header.WriteString(xml.Header)
+	header.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2">` + "\n<Document>\n<name>Stations</name>\n")
+	header.WriteString(`<Style id="station"><IconStyle><scale>0.8</scale></IconStyle></Style>` + "\n")
+	for _, style := range kmlClassStyles {
+		fmt.Fprintf(&header, `<Style id="class-%d"><IconStyle><color>%s</color><scale>%g</scale></IconStyle></Style>`+"\n",
+			style.Class, style.Color, style.Scale)
+	}
+
+	_, err := io.WriteString(e.w, header.String())
+	return err
+}
+
+func (e *kmlStationExporter) WriteStation(station StationModel) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	style := "station"
+	for _, classStyle := range kmlClassStyles {
+		if classStyle.Class == station.Class {
+			style = fmt.Sprintf("class-%d", station.Class)
+			break
+		}
+	}
+
+	_, err := fmt.Fprintf(e.w,
+		"<Placemark id=\"station-%d\"><name>%s</name><description>%s</description><styleUrl>#%s</styleUrl>"+
+			"<ExtendedData><Data name=\"station_id\"><value>%d</value></Data><Data name=\"name\"><value>%s</value></Data>"+
+			"<Data name=\"en_name\"><value>%s</value></Data><Data name=\"class\"><value>%d</value></Data></ExtendedData>"+
+			"<Point><coordinates>%s,%s</coordinates></Point></Placemark>\n",
+		station.StationID, xmlText(station.Name), xmlText(station.EnName), style,
+		station.StationID, xmlText(station.Name), xmlText(station.EnName), station.Class,
+		strconv.FormatFloat(station.Long, 'f', -1, 64), strconv.FormatFloat(station.Lat, 'f', -1, 64))
+	return err
+}
+
+func (e *kmlStationExporter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(e.w, "</Document>\n</kml>\n")
+	return err
+}
+
+// ---------------------------------- GPX -------------------------
+type gpxStationExporter struct {
+	w       io.Writer
+	started bool
+}
+
+func newGPXStationExporter(w io.Writer) StationExporter {
+	return &gpxStationExporter{w: w}
+}
+
+func (e *gpxStationExporter) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	_, err := io.WriteString(e.w, xml.Header+
+		`<gpx version="1.1" creator="go_spinsoft" xmlns="http://www.topografix.com/GPX/1/1">`+"\n")
+	return err
+}
+
+func (e *gpxStationExporter) WriteStation(station StationModel) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(e.w,
+		"<wpt lat=\"%s\" lon=\"%s\"><name>%s</name><desc>%s</desc><type>class %d</type></wpt>\n",
+		strconv.FormatFloat(station.Lat, 'f', -1, 64), strconv.FormatFloat(station.Long, 'f', -1, 64),
+		xmlText(station.Name), xmlText(station.EnName), station.Class)
+	return err
+}
+
+func (e *gpxStationExporter) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(e.w, "</gpx>\n")
+	return err
+}
+
+func xmlText(value string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
@@ -0,0 +1,221 @@
+package station
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+const (
+	GeometryPolygon      = "Polygon"
+	GeometryMultiPolygon = "MultiPolygon"
+)
+
+// GeoJSONGeometry is a Polygon or MultiPolygon request body. A Feature is
+// accepted and its geometry used.
+type GeoJSONGeometry struct {
+	Type        string           `json:"type"`
+	Coordinates json.RawMessage  `json:"coordinates"`
+	Geometry    *GeoJSONGeometry `json:"geometry,omitempty"`
+}
+
+type geoWithinShape struct {
+	Type        string      `bson:"type" json:"type"`
+	Coordinates interface{} `bson:"coordinates" json:"coordinates"`
+}
+
+// shape validates the geometry and converts it to the form $geoWithin takes.
+func (g *GeoJSONGeometry) shape() (*geoWithinShape, error) {
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, fmt.Errorf("%w: feature has no geometry", ErrInvalidStation)
+		}
+		return g.Geometry.shape()
+	}
+
+	switch g.Type {
+	case GeometryPolygon:
+		var polygon [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("%w: invalid Polygon coordinates", ErrInvalidStation)
+		}
+		if err := validatePolygon(polygon); err != nil {
+			return nil, err
+		}
+		return &geoWithinShape{Type: GeometryPolygon, Coordinates: polygon}, nil
+
+	case GeometryMultiPolygon:
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("%w: invalid MultiPolygon coordinates", ErrInvalidStation)
+		}
+		if len(polygons) == 0 {
+			return nil, fmt.Errorf("%w: MultiPolygon has no polygons", ErrInvalidStation)
+		}
+		for _, polygon := range polygons {
+			if err := validatePolygon(polygon); err != nil {
+				return nil, err
+			}
+		}
+		return &geoWithinShape{Type: GeometryMultiPolygon, Coordinates: polygons}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: geometry type must be Polygon or MultiPolygon", ErrInvalidStation)
+	}
+}
+
+func validatePolygon(polygon [][][]float64) error {
+	if len(polygon) == 0 {
+		return fmt.Errorf("%w: polygon has no rings", ErrInvalidStation)
+	}
+
+	for _, ring := range polygon {
+		if len(ring) < 4 {
+			return fmt.Errorf("%w: polygon rings need at least 4 positions", ErrInvalidStation)
+		}
+
+		for _, position := range ring {
+			if len(position) < 2 {
+				return fmt.Errorf("%w: positions must be [long, lat]", ErrInvalidStation)
+			}
+			if err := utils.ValidateCoordinates(position[1], position[0]); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidStation, err)
+			}
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("%w: polygon rings must be closed", ErrInvalidStation)
+		}
+	}
+
+	return nil
+}
+
+// ParseBBox reads minLon,minLat,maxLon,maxLat.
+func ParseBBox(value string) ([4]float64, error) {
+	var bbox [4]float64
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return bbox, fmt.Errorf("%w: bbox must be minLon,minLat,maxLon,maxLat", ErrInvalidStation)
+	}
+
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(n) {
+			return bbox, fmt.Errorf("%w: bbox must be minLon,minLat,maxLon,maxLat", ErrInvalidStation)
+		}
+		bbox[i] = n
+	}
+
+	for _, corner := range [][2]float64{{bbox[0], bbox[1]}, {bbox[2], bbox[3]}} {
+		if err := utils.ValidateCoordinates(corner[1], corner[0]); err != nil {
+			return bbox, fmt.Errorf("%w: %v", ErrInvalidStation, err)
+		}
+	}
+
+	if bbox[0] >= bbox[2] || bbox[1] >= bbox[3] {
+		return bbox, fmt.Errorf("%w: bbox min values must be less than max values", ErrInvalidStation)
+	}
+
+	// $geoWithin rejects polygons larger than a hemisphere
+	if bbox[2]-bbox[0] >= 180 {
+		return bbox, fmt.Errorf("%w: bbox must be less than 180 degrees wide", ErrInvalidStation)
+	}
+
+	return bbox, nil
+}
+
+// bboxShape turns a bbox into a polygon. Its edges are geodesics, which bow
+// slightly away from the parallels on wide boxes.
+func bboxShape(bbox [4]float64) *geoWithinShape {
+	minLon, minLat, maxLon, maxLat := bbox[0], bbox[1], bbox[2], bbox[3]
+
+	return &geoWithinShape{
+		Type: GeometryPolygon,
+		Coordinates: [][][]float64{{
+			{minLon, minLat},
+			{maxLon, minLat},
+			{maxLon, maxLat},
+			{minLon, maxLat},
+			{minLon, minLat},
+		}},
+	}
+}
+
+// contains tests a point against the shape for backends without $geoWithin.
+// Edges are treated as straight lines in longitude and latitude rather than
+// geodesics, which only matters for very large polygons.
+func (s *geoWithinShape) contains(lat, long float64) bool {
+	switch coordinates := s.Coordinates.(type) {
+	case [][][]float64:
+		return polygonContains(coordinates, lat, long)
+	case [][][][]float64:
+		for _, polygon := range coordinates {
+			if polygonContains(polygon, lat, long) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bounds returns minLon,minLat,maxLon,maxLat of the outer rings.
+func (s *geoWithinShape) bounds() [4]float64 {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	extend := func(polygon [][][]float64) {
+		if len(polygon) == 0 {
+			return
+		}
+		for _, position := range polygon[0] {
+			box[0] = math.Min(box[0], position[0])
+			box[1] = math.Min(box[1], position[1])
+			box[2] = math.Max(box[2], position[0])
+			box[3] = math.Max(box[3], position[1])
+		}
+	}
+
+	switch coordinates := s.Coordinates.(type) {
+	case [][][]float64:
+		extend(coordinates)
+	case [][][][]float64:
+		for _, polygon := range coordinates {
+			extend(polygon)
+		}
+	}
+
+	return box
+}
+
+// polygonContains treats every ring after the first as a hole.
+func polygonContains(polygon [][][]float64, lat, long float64) bool {
+	for i, ring := range polygon {
+		inside := ringContains(ring, lat, long)
+		if i == 0 && !inside {
+			return false
+		}
+		if i > 0 && inside {
+			return false
+		}
+	}
+	return len(polygon) > 0
+}
+
+func ringContains(ring [][]float64, lat, long float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > lat) != (yj > lat) && long < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,56 @@
+package station
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StationChangeType string
+
+const (
+	StationChangeCreate StationChangeType = "create"
+	StationChangeUpdate StationChangeType = "update"
+	StationChangeDelete StationChangeType = "delete"
+	StationChangeImport StationChangeType = "import"
+)
+
+type StationHistoryModel struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	StationID   int                 `bson:"station_id" json:"station_id"`
+	Version     int                 `bson:"version" json:"version"`
+	ChangeType  StationChangeType   `bson:"change_type" json:"change_type"`
+	ChangedBy   string              `bson:"changed_by" json:"changed_by"`
+	ImportJobID *primitive.ObjectID `bson:"import_job_id,omitempty" json:"import_job_id,omitempty"`
+	Changes     []StationFieldDiff  `bson:"changes" json:"changes"`
+	Snapshot    StationModel        `bson:"snapshot" json:"snapshot"`
+	ChangedAt   primitive.DateTime  `bson:"changed_at" json:"changed_at"`
+}
+
+// ---------------------------------- Change Context -------------------------
+type stationChangeKey struct{}
+
+type stationChange struct {
+	actor       string
+	importJobID *primitive.ObjectID
+}
+
+func withStationChange(ctx context.Context, actor string, importJobID *primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, stationChangeKey{}, stationChange{
+		actor:       actor,
+		importJobID: importJobID,
+	})
+}
+
+// WithChangedBy tags station writes made with ctx, like the X-Changed-By
+// header does for API requests.
+func WithChangedBy(ctx context.Context, actor string) context.Context {
+	return withStationChange(ctx, actor, nil)
+}
+
+func stationChangeFrom(ctx context.Context) stationChange {
+	if change, ok := ctx.Value(stationChangeKey{}).(stationChange); ok {
+		return change
+	}
+	return stationChange{actor: "system"}
+}
@@ -0,0 +1,144 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StationHistoryRepository interface {
+	InsertMany(ctx context.Context, entries []StationHistoryModel) error
+	FindByStation(ctx context.Context, stationID int, limit int) ([]StationHistoryModel, error)
+	FindAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error)
+	FindFirstAfter(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error)
+	NextVersions(ctx context.Context, stationIDs []int) (map[int]int, error)
+}
+
+type stationHistoryRepositoryType struct {
+	collection *mongo.Collection
+	counters   *mongo.Collection
+}
+
+// NewStationHistoryRepository keeps each station's last version number in
+// counters, which migration 0007 seeds from the existing history.
+func NewStationHistoryRepository(collection, counters *mongo.Collection) StationHistoryRepository {
+	return &stationHistoryRepositoryType{
+		collection: collection,
+		counters:   counters,
+	}
+}
+
+// ---------------------------------- Insert Many -------------------------
+func (r *stationHistoryRepositoryType) InsertMany(ctx context.Context, entries []StationHistoryModel) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		documents[i] = entry
+	}
+
+	if _, err := r.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to insert station history: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find By Station -------------------------
+func (r *stationHistoryRepositoryType) FindByStation(ctx context.Context, stationID int, limit int) ([]StationHistoryModel, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"station_id": stationID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []StationHistoryModel{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ---------------------------------- Find As Of -------------------------
+func (r *stationHistoryRepositoryType) FindAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error) {
+	filter := bson.M{
+		"station_id": stationID,
+		"changed_at": bson.M{"$lte": primitive.NewDateTimeFromTime(asOf)},
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var entry StationHistoryModel
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station history: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ---------------------------------- Find First After -------------------------
+
+// FindFirstAfter returns the earliest version recorded after asOf. Its Old
+// values are the station as it stood at asOf.
+func (r *stationHistoryRepositoryType) FindFirstAfter(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error) {
+	filter := bson.M{
+		"station_id": stationID,
+		"changed_at": bson.M{"$gt": primitive.NewDateTimeFromTime(asOf)},
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: 1}})
+
+	var entry StationHistoryModel
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station history: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ---------------------------------- Next Versions -------------------------
+
+// NextVersions reserves the next version number of each station. The counter
+// is incremented atomically, so concurrent writers never get the same number.
+func (r *stationHistoryRepositoryType) NextVersions(ctx context.Context, stationIDs []int) (map[int]int, error) {
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	versions := make(map[int]int, len(stationIDs))
+	for _, stationID := range stationIDs {
+		var counter struct {
+			Version int `bson:"version"`
+		}
+
+		update := bson.M{"$inc": bson.M{"version": 1}}
+		if err := r.counters.FindOneAndUpdate(ctx, bson.M{"_id": stationID}, update, opts).Decode(&counter); err != nil {
+			return nil, fmt.Errorf("failed to reserve history version: %w", err)
+		}
+
+		versions[stationID] = counter.Version
+	}
+
+	return versions, nil
+}
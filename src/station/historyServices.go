@@ -0,0 +1,300 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type stationVersionChange struct {
+	before *StationModel
+	after  StationModel
+}
+
+// ---------------------------------- Get Station History -------------------------
+func (s *stationServiceType) GetStationHistory(ctx context.Context, stationID int, limit int) (*StationHistoryResponse, error) {
+	if limit < 1 || limit > 100 {
+		return nil, fmt.Errorf("%w: invalid limit: must be between 1 and 100", ErrInvalidStation)
+	}
+
+	entries, err := s.historyRepo.FindByStation(ctx, stationID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		if _, err := s.repo.FindByID(ctx, stationID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StationHistoryResponse{
+		Success:   true,
+		StationID: stationID,
+		Data:      entries,
+	}, nil
+}
+
+// ---------------------------------- Get Station As Of -------------------------
+func (s *stationServiceType) GetStationAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationResponse, error) {
+	if s.historyRepo == nil {
+		return nil, fmt.Errorf("%w: station history is not kept by this deployment", ErrInvalidStation)
+	}
+
+	entry, err := s.historyRepo.FindAsOf(ctx, stationID, asOf)
+	if errors.Is(err, ErrStationNotFound) {
+		return s.stationBeforeHistory(ctx, stationID, asOf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.ChangeType == StationChangeDelete {
+		return nil, fmt.Errorf("%w: deleted at %s", ErrStationNotFound, entry.ChangedAt.Time().Format(time.RFC3339))
+	}
+
+	snapshot := entry.Snapshot
+	return &StationResponse{
+		Success: true,
+		Data:    &snapshot,
+	}, nil
+}
+
+// stationBeforeHistory answers for stations with no version at or before
+// asOf. The first version after asOf is rolled back through the Old values of
+// its changes; only a station with no history at all is read from the current
+// document.
+func (s *stationServiceType) stationBeforeHistory(ctx context.Context, stationID int, asOf time.Time) (*StationResponse, error) {
+	entry, err := s.historyRepo.FindFirstAfter(ctx, stationID, asOf)
+	if errors.Is(err, ErrStationNotFound) {
+		return s.currentStationAsOf(ctx, stationID, asOf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	station := entry.Snapshot
+	if station.CreatedAt.Time().After(asOf) {
+		return nil, fmt.Errorf("%w: no version recorded at %s", ErrStationNotFound, asOf.Format(time.RFC3339))
+	}
+
+	moved, relocated := false, false
+	for _, change := range entry.Changes {
+		setStationField(&station, change.Field, change.Old)
+		moved = moved || change.Field == "lat" || change.Field == "long"
+		relocated = relocated || change.Field == "location"
+	}
+	// Versions recorded before locations were diffed only have lat and long
+	if moved && !relocated && station.coordinateError() == "" {
+		station.syncLocation()
+	} else if moved && !relocated {
+		station.Location = nil
+	}
+	station.DeletedAt = nil
+	// When it was last updated before asOf is not recorded
+	station.UpdatedAt = station.CreatedAt
+
+	return &StationResponse{
+		Success: true,
+		Data:    &station,
+	}, nil
+}
+
+// currentStationAsOf answers for stations that have not changed since history
+// was introduced. The current document stands in when the station already
+// existed at asOf.
+func (s *stationServiceType) currentStationAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationResponse, error) {
+	station, err := s.repo.FindByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if station.CreatedAt.Time().After(asOf) {
+		return nil, fmt.Errorf("%w: no version recorded at %s", ErrStationNotFound, asOf.Format(time.RFC3339))
+	}
+	if station.DeletedAt != nil && !station.DeletedAt.Time().After(asOf) {
+		return nil, fmt.Errorf("%w: deleted at %s", ErrStationNotFound, station.DeletedAt.Time().Format(time.RFC3339))
+	}
+
+	return &StationResponse{
+		Success: true,
+		Data:    station,
+	}, nil
+}
+
+// setStationField sets one of stationDiffFields from a history value, which
+// comes back from BSON as int32, int64 or float64 for numbers.
+func setStationField(station *StationModel, field string, value interface{}) {
+	text, _ := value.(string)
+
+	switch field {
+	case "station_code":
+		station.StationCode = utils.ToInt(value)
+	case "name":
+		station.Name = text
+	case "en_name":
+		station.EnName = text
+	case "th_short":
+		station.ThShort = text
+	case "en_short":
+		station.EnShort = text
+	case "chname":
+		station.ChName = text
+	case "controldivision":
+		station.ControlDiv = utils.ToInt(value)
+	case "exact_km":
+		station.ExactKM = utils.ToInt(value)
+	case "exact_distance":
+		station.ExactDistance = utils.ToInt(value)
+	case "km":
+		station.KM = utils.ToInt(value)
+	case "class":
+		station.Class = utils.ToInt(value)
+	case "lat":
+		station.Lat = utils.ToFloat64(value)
+	case "long":
+		station.Long = utils.ToFloat64(value)
+	case "active":
+		station.Active = utils.ToInt(value)
+	case "giveway":
+		station.Giveway = utils.ToInt(value)
+	case "dual_track":
+		station.DualTrack = utils.ToInt(value)
+	case "comment":
+		station.Comment = text
+	case "location":
+		station.Location = locationFromHistory(value)
+	}
+}
+
+// recordStationChange fails the request when the version cannot be written,
+// rather than leave a change out of the history.
+func (s *stationServiceType) recordStationChange(ctx context.Context, changeType StationChangeType, before *StationModel, after StationModel) error {
+	change := stationVersionChange{before: before, after: after}
+	if err := recordHistory(ctx, s.historyRepo, changeType, []stationVersionChange{change}); err != nil {
+		return fmt.Errorf("station %d was saved but its history was not recorded: %w", after.StationID, err)
+	}
+	return nil
+}
+
+// stationHistoryDiffs adds the location to the import diff fields, so a
+// reindex that only rebuilds it is still recorded and can be rolled back.
+func stationHistoryDiffs(old, station StationModel) []StationFieldDiff {
+	diffs := diffStationFields(old, station)
+	if !sameLocation(old.Location, station.Location) {
+		diffs = append(diffs, StationFieldDiff{
+			Field: "location",
+			Old:   locationCoordinates(old.Location),
+			New:   locationCoordinates(station.Location),
+		})
+	}
+	return diffs
+}
+
+func locationCoordinates(location *GeoJSONPointModel) []float64 {
+	if location == nil {
+		return nil
+	}
+	return location.Coordinates
+}
+
+// locationFromHistory rebuilds the point from recorded coordinates, which
+// come back from BSON as primitive.A.
+func locationFromHistory(value interface{}) *GeoJSONPointModel {
+	var coordinates []float64
+	switch v := value.(type) {
+	case []float64:
+		coordinates = v
+	case primitive.A:
+		for _, coordinate := range v {
+			coordinates = append(coordinates, utils.ToFloat64(coordinate))
+		}
+	}
+
+	if len(coordinates) < 2 {
+		return nil
+	}
+	return &GeoJSONPointModel{Type: "Point", Coordinates: coordinates}
+}
+
+// recordHistory writes one version per changed station. Stations whose fields
+// did not change are skipped, and only the last change per station is kept.
+func recordHistory(ctx context.Context, historyRepo StationHistoryRepository, changeType StationChangeType, changes []stationVersionChange) error {
+	// Offline stores keep no history
+	if historyRepo == nil {
+		return nil
+	}
+
+	latest := make(map[int]stationVersionChange, len(changes))
+	order := make([]int, 0, len(changes))
+	for _, change := range changes {
+		if _, ok := latest[change.after.StationID]; !ok {
+			order = append(order, change.after.StationID)
+		}
+		latest[change.after.StationID] = change
+	}
+
+	info := stationChangeFrom(ctx)
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	var entries []StationHistoryModel
+	for _, stationID := range order {
+		change := latest[stationID]
+
+		var diffs []StationFieldDiff
+		switch {
+		case changeType == StationChangeDelete:
+			diffs = []StationFieldDiff{}
+		case change.before == nil:
+			diffs = stationHistoryDiffs(StationModel{}, change.after)
+		default:
+			diffs = stationHistoryDiffs(*change.before, change.after)
+			if len(diffs) == 0 {
+				continue
+			}
+		}
+
+		snapshot := change.after
+		snapshot.UpdatedAt = now
+		if change.before != nil {
+			snapshot.ID = change.before.ID
+			snapshot.CreatedAt = change.before.CreatedAt
+		} else if snapshot.CreatedAt == 0 {
+			snapshot.CreatedAt = now
+		}
+
+		entries = append(entries, StationHistoryModel{
+			StationID:   stationID,
+			ChangeType:  changeType,
+			ChangedBy:   info.actor,
+			ImportJobID: info.importJobID,
+			Changes:     diffs,
+			Snapshot:    snapshot,
+			ChangedAt:   now,
+		})
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Versions are reserved only for stations that actually changed
+	stationIDs := make([]int, len(entries))
+	for i, entry := range entries {
+		stationIDs[i] = entry.StationID
+	}
+
+	versions, err := historyRepo.NextVersions(ctx, stationIDs)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Version = versions[entries[i].StationID]
+	}
+
+	return historyRepo.InsertMany(ctx, entries)
+}
@@ -0,0 +1,258 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeHistoryRepository keeps versions in memory, ordered as inserted.
+type fakeHistoryRepository struct {
+	entries   []StationHistoryModel
+	counters  map[int]int
+	insertErr error
+}
+
+func (r *fakeHistoryRepository) InsertMany(ctx context.Context, entries []StationHistoryModel) error {
+	if r.insertErr != nil {
+		return r.insertErr
+	}
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func (r *fakeHistoryRepository) FindByStation(ctx context.Context, stationID int, limit int) ([]StationHistoryModel, error) {
+	entries := r.station(stationID)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Version > entries[j].Version })
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func (r *fakeHistoryRepository) FindAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error) {
+	var found *StationHistoryModel
+	for _, entry := range r.station(stationID) {
+		if !entry.ChangedAt.Time().After(asOf) && (found == nil || entry.Version > found.Version) {
+			entry := entry
+			found = &entry
+		}
+	}
+	if found == nil {
+		return nil, ErrStationNotFound
+	}
+	return found, nil
+}
+
+func (r *fakeHistoryRepository) FindFirstAfter(ctx context.Context, stationID int, asOf time.Time) (*StationHistoryModel, error) {
+	var found *StationHistoryModel
+	for _, entry := range r.station(stationID) {
+		if entry.ChangedAt.Time().After(asOf) && (found == nil || entry.Version < found.Version) {
+			entry := entry
+			found = &entry
+		}
+	}
+	if found == nil {
+		return nil, ErrStationNotFound
+	}
+	return found, nil
+}
+
+func (r *fakeHistoryRepository) NextVersions(ctx context.Context, stationIDs []int) (map[int]int, error) {
+	if r.counters == nil {
+		r.counters = make(map[int]int)
+	}
+
+	versions := make(map[int]int, len(stationIDs))
+	for _, stationID := range stationIDs {
+		r.counters[stationID]++
+		versions[stationID] = r.counters[stationID]
+	}
+	return versions, nil
+}
+
+func (r *fakeHistoryRepository) station(stationID int) []StationHistoryModel {
+	var entries []StationHistoryModel
+	for _, entry := range r.entries {
+		if entry.StationID == stationID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func newHistoryTestService(t *testing.T, historyRepo *fakeHistoryRepository) (StationService, StationRepository) {
+	t.Helper()
+
+	repo, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+	return NewStationService(repo, nil, historyRepo, nil, nil), repo
+}
+
+func TestGetStationAsOfBeforeHistory(t *testing.T) {
+	ctx := context.Background()
+	historyRepo := &fakeHistoryRepository{}
+	service, repo := newHistoryTestService(t, historyRepo)
+
+	current, err := repo.FindByID(ctx, 1001)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	// The memory store stamps its load time; the station is older than history
+	current.CreatedAt = primitive.NewDateTimeFromTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Station 1001 predates history; its first version moved and reactivated it
+	updatedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	historyRepo.entries = append(historyRepo.entries, StationHistoryModel{
+		StationID:  1001,
+		Version:    1,
+		ChangeType: StationChangeUpdate,
+		ChangedBy:  "api",
+		Changes: []StationFieldDiff{
+			{Field: "lat", Old: 13.7, New: current.Lat},
+			{Field: "long", Old: 100.5, New: current.Long},
+			{Field: "active", Old: int32(0), New: int32(current.Active)},
+			{Field: "comment", Old: "moved", New: current.Comment},
+		},
+		Snapshot:  *current,
+		ChangedAt: primitive.NewDateTimeFromTime(updatedAt),
+	})
+
+	t.Run("before the first version", func(t *testing.T) {
+		response, err := service.GetStationAsOf(ctx, 1001, updatedAt.Add(-24*time.Hour))
+		if err != nil {
+			t.Fatalf("GetStationAsOf: %v", err)
+		}
+
+		station := response.Data
+		if station.Lat != 13.7 || station.Long != 100.5 || station.Active != 0 || station.Comment != "moved" {
+			t.Errorf("got lat %v long %v active %d comment %q, want the values before the update",
+				station.Lat, station.Long, station.Active, station.Comment)
+		}
+		if station.Location == nil || station.Location.Coordinates[0] != 100.5 || station.Location.Coordinates[1] != 13.7 {
+			t.Errorf("location = %+v, want the old coordinates", station.Location)
+		}
+		if station.Name != current.Name {
+			t.Errorf("name = %q, want unchanged %q", station.Name, current.Name)
+		}
+	})
+
+	t.Run("after the first version", func(t *testing.T) {
+		response, err := service.GetStationAsOf(ctx, 1001, updatedAt.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("GetStationAsOf: %v", err)
+		}
+		if response.Data.Lat != current.Lat || response.Data.Active != current.Active {
+			t.Errorf("got lat %v active %d, want the snapshot", response.Data.Lat, response.Data.Active)
+		}
+	})
+
+	t.Run("station without history", func(t *testing.T) {
+		response, err := service.GetStationAsOf(ctx, 1002, time.Now())
+		if err != nil {
+			t.Fatalf("GetStationAsOf: %v", err)
+		}
+		if response.Data.StationID != 1002 {
+			t.Errorf("got station %d, want the current 1002", response.Data.StationID)
+		}
+	})
+
+	t.Run("created after as_of", func(t *testing.T) {
+		created := *current
+		created.StationID = 2001
+		created.CreatedAt = primitive.NewDateTimeFromTime(updatedAt)
+		historyRepo.entries = append(historyRepo.entries, StationHistoryModel{
+			StationID:  2001,
+			Version:    1,
+			ChangeType: StationChangeCreate,
+			Changes:    diffStationFields(StationModel{}, created),
+			Snapshot:   created,
+			ChangedAt:  created.CreatedAt,
+		})
+
+		_, err := service.GetStationAsOf(ctx, 2001, updatedAt.Add(-time.Hour))
+		if !errors.Is(err, ErrStationNotFound) {
+			t.Fatalf("GetStationAsOf() = %v, want ErrStationNotFound", err)
+		}
+	})
+}
+
+func TestRecordStationChangeVersions(t *testing.T) {
+	ctx := context.Background()
+	historyRepo := &fakeHistoryRepository{}
+	service, _ := newHistoryTestService(t, historyRepo)
+
+	for _, comment := range []string{"first", "second"} {
+		comment := comment
+		if _, err := service.PatchStation(ctx, 1001, StationPatchRequest{Comment: &comment}); err != nil {
+			t.Fatalf("PatchStation: %v", err)
+		}
+	}
+
+	entries, _ := historyRepo.FindByStation(ctx, 1001, 10)
+	if len(entries) != 2 || entries[0].Version != 2 || entries[1].Version != 1 {
+		t.Fatalf("got versions %+v, want 2 then 1", entries)
+	}
+
+	// A lost history write fails the request instead of only being logged
+	historyRepo.insertErr = errors.New("write conflict")
+	comment := "third"
+	_, err := service.PatchStation(ctx, 1001, StationPatchRequest{Comment: &comment})
+	if err == nil || !strings.Contains(err.Error(), "history was not recorded") {
+		t.Fatalf("PatchStation() = %v, want the history error", err)
+	}
+}
+
+func TestReindexStationsRecordsHistory(t *testing.T) {
+	ctx := WithChangedBy(context.Background(), "cli")
+	historyRepo := &fakeHistoryRepository{}
+	service, repo := newHistoryTestService(t, historyRepo)
+
+	// Station 1002 was edited outside the service and lost its location
+	stale, err := repo.FindByID(ctx, 1002)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	stale.Location = nil
+	if err := repo.Update(ctx, stale); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// Keep the reindex clear of the load time the memory store stamps
+	time.Sleep(2 * time.Millisecond)
+
+	result, err := service.ReindexStations(ctx)
+	if err != nil {
+		t.Fatalf("ReindexStations: %v", err)
+	}
+	if result.UpdatedCount != 1 {
+		t.Fatalf("updated %d stations, want 1", result.UpdatedCount)
+	}
+
+	entries, _ := historyRepo.FindByStation(ctx, 1002, 10)
+	if len(entries) != 1 {
+		t.Fatalf("got %d history entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.ChangeType != StationChangeUpdate || entry.ChangedBy != "cli" {
+		t.Errorf("got %s by %q, want update by cli", entry.ChangeType, entry.ChangedBy)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].Field != "location" {
+		t.Fatalf("changes = %+v, want only the location", entry.Changes)
+	}
+
+	// Rolling the version back restores the missing location
+	response, err := service.GetStationAsOf(ctx, 1002, entry.ChangedAt.Time().Add(-time.Millisecond))
+	if err != nil {
+		t.Fatalf("GetStationAsOf: %v", err)
+	}
+	if response.Data.Location != nil {
+		t.Errorf("location = %+v, want none before the reindex", response.Data.Location)
+	}
+}
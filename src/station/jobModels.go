@@ -0,0 +1,37 @@
+package station
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+type ImportJobModel struct {
+	ID                 primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Status             ImportJobStatus      `bson:"status" json:"status"`
+	Request            StationImportRequest `bson:"request" json:"request"`
+	TotalCount         int                  `bson:"total_count" json:"total_count"`
+	ProcessedCount     int                  `bson:"processed_count" json:"processed_count"`
+	ImportedCount      int                  `bson:"imported_count" json:"imported_count"`
+	InvalidCoordinates int                  `bson:"invalid_coordinates" json:"invalid_coordinates"`
+	Batches            []StationImportBatch `bson:"batches" json:"batches"`
+	MissingStationIDs  []int                `bson:"missing_station_ids" json:"missing_station_ids"`
+	RejectedCount      int                  `bson:"rejected_count" json:"rejected_count"`
+	Report             []StationImportIssue `bson:"report" json:"report"`
+	ReportTotal        int                  `bson:"report_total" json:"report_total"`
+	ReportTruncated    bool                 `bson:"report_truncated" json:"report_truncated"`
+	Errors             []string             `bson:"errors" json:"errors"`
+	Message            string               `bson:"message" json:"message"`
+	Attempts           int                  `bson:"attempts" json:"attempts"`
+	CreatedAt          primitive.DateTime   `bson:"created_at" json:"created_at"`
+	StartedAt          *primitive.DateTime  `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt         *primitive.DateTime  `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	DurationMs         int64                `bson:"duration_ms" json:"duration_ms"`
+	Owner              string               `bson:"owner,omitempty" json:"owner,omitempty"`
+	LeaseExpiresAt     *primitive.DateTime  `bson:"lease_expires_at,omitempty" json:"lease_expires_at,omitempty"`
+}
@@ -0,0 +1,253 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrImportJobNotFound  = errors.New("import job not found")
+	ErrImportJobLeaseLost = errors.New("import job lease lost")
+)
+
+type ImportJobRepository interface {
+	Insert(ctx context.Context, job *ImportJobModel) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*ImportJobModel, error)
+	ClaimNext(ctx context.Context, owner string, lease time.Duration) (*ImportJobModel, error)
+	RenewLease(ctx context.Context, id primitive.ObjectID, owner string, lease time.Duration) error
+	Release(ctx context.Context, id primitive.ObjectID, owner string) error
+	UpdateProgress(ctx context.Context, id primitive.ObjectID, progress StationImportResponse) error
+	Finish(ctx context.Context, id primitive.ObjectID, owner string, result StationImportResponse, jobErr error) error
+}
+
+type importJobRepositoryType struct {
+	collection *mongo.Collection
+}
+
+func NewImportJobRepository(collection *mongo.Collection) ImportJobRepository {
+	return &importJobRepositoryType{
+		collection: collection,
+	}
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *importJobRepositoryType) Insert(ctx context.Context, job *ImportJobModel) error {
+	job.ID = primitive.NilObjectID
+	job.Status = ImportJobPending
+	job.Batches = []StationImportBatch{}
+	job.MissingStationIDs = []int{}
+	job.Report = []StationImportIssue{}
+	job.Errors = []string{}
+	job.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.collection.InsertOne(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to insert import job: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		job.ID = id
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *importJobRepositoryType) FindByID(ctx context.Context, id primitive.ObjectID) (*ImportJobModel, error) {
+	var job ImportJobModel
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrImportJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find import job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ---------------------------------- Claim Next -------------------------
+
+// ClaimNext takes the oldest pending job, or a running job whose lease has
+// expired because its worker stopped renewing it. Running jobs without a
+// lease were claimed before leases existed and are treated as expired.
+func (r *importJobRepositoryType) ClaimNext(ctx context.Context, owner string, lease time.Duration) (*ImportJobModel, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": ImportJobPending},
+			bson.M{
+				"status": ImportJobRunning,
+				"$or": bson.A{
+					bson.M{"lease_expires_at": bson.M{"$lt": primitive.NewDateTimeFromTime(now)}},
+					bson.M{"lease_expires_at": bson.M{"$exists": false}},
+				},
+			},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":           ImportJobRunning,
+			"started_at":       primitive.NewDateTimeFromTime(now),
+			"owner":            owner,
+			"lease_expires_at": primitive.NewDateTimeFromTime(now.Add(lease)),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job ImportJobModel
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim import job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ---------------------------------- Lease -------------------------
+
+// RenewLease extends the lease of a job the owner still holds.
+func (r *importJobRepositoryType) RenewLease(ctx context.Context, id primitive.ObjectID, owner string, lease time.Duration) error {
+	filter := bson.M{"_id": id, "owner": owner, "status": ImportJobRunning}
+	update := bson.M{
+		"$set": bson.M{"lease_expires_at": primitive.NewDateTimeFromTime(time.Now().Add(lease))},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to renew import job lease: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrImportJobLeaseLost
+	}
+
+	return nil
+}
+
+// Release puts a job the owner holds back in the queue, for a worker that
+// stops before finishing it.
+func (r *importJobRepositoryType) Release(ctx context.Context, id primitive.ObjectID, owner string) error {
+	filter := bson.M{"_id": id, "owner": owner, "status": ImportJobRunning}
+	update := bson.M{
+		"$set":   bson.M{"status": ImportJobPending},
+		"$unset": bson.M{"started_at": "", "owner": "", "lease_expires_at": ""},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release import job: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Update Progress -------------------------
+func (r *importJobRepositoryType) UpdateProgress(ctx context.Context, id primitive.ObjectID, progress StationImportResponse) error {
+	update := bson.M{
+		"$set": bson.M{
+			"total_count":         progress.TotalCount,
+			"processed_count":     progress.ProcessedCount,
+			"imported_count":      progress.ImportedCount,
+			"invalid_coordinates": progress.InvalidCoordinates,
+			"message":             progress.Message,
+			"batches":             progress.Batches,
+			"rejected_count":      progress.RejectedCount,
+			"report":              progress.Report,
+			"report_total":        progress.ReportTotal,
+			"report_truncated":    progress.ReportTruncated,
+		},
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Finish -------------------------
+// Finish records the result of a job the owner still holds.
+func (r *importJobRepositoryType) Finish(ctx context.Context, id primitive.ObjectID, owner string, result StationImportResponse, jobErr error) error {
+	job, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	finishedAt := time.Now()
+	status := ImportJobCompleted
+	errorMessages := job.Errors
+	if errorMessages == nil {
+		errorMessages = []string{}
+	}
+
+	if jobErr != nil {
+		status = ImportJobFailed
+		errorMessages = append(errorMessages, jobErr.Error())
+	} else if !result.Success {
+		status = ImportJobFailed
+		errorMessages = append(errorMessages, result.Message)
+	}
+
+	if result.Batches == nil {
+		result.Batches = job.Batches
+	}
+	if result.MissingStationIDs == nil {
+		result.MissingStationIDs = []int{}
+	}
+	if result.Report == nil {
+		result.Report = job.Report
+	}
+
+	var durationMs int64
+	if job.StartedAt != nil {
+		durationMs = finishedAt.Sub(job.StartedAt.Time()).Milliseconds()
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":              status,
+			"total_count":         result.TotalCount,
+			"processed_count":     result.ProcessedCount,
+			"imported_count":      result.ImportedCount,
+			"invalid_coordinates": result.InvalidCoordinates,
+			"message":             result.Message,
+			"batches":             result.Batches,
+			"missing_station_ids": result.MissingStationIDs,
+			"rejected_count":      result.RejectedCount,
+			"report":              result.Report,
+			"report_total":        result.ReportTotal,
+			"report_truncated":    result.ReportTruncated,
+			"errors":              errorMessages,
+			"finished_at":         primitive.NewDateTimeFromTime(finishedAt),
+			"duration_ms":         durationMs,
+		},
+		"$unset": bson.M{"lease_expires_at": ""},
+	}
+
+	filter := bson.M{"_id": id, "owner": owner, "status": ImportJobRunning}
+	updateResult, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to finish import job: %w", err)
+	}
+	if updateResult.MatchedCount == 0 {
+		return ErrImportJobLeaseLost
+	}
+
+	return nil
+}
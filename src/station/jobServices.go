@@ -0,0 +1,237 @@
+package station
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	importJobPollInterval = 5 * time.Second
+	// importJobLease is how long a claimed job stays with its worker without
+	// a renewal before another instance may take it over
+	importJobLease        = 2 * time.Minute
+	importJobLeaseRenewal = importJobLease / 4
+)
+
+// ---------------------------------- Create Import Job -------------------------
+func (s *stationServiceType) CreateImportJob(ctx context.Context, req StationImportRequest) (*ImportJobResponse, error) {
+	if req.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidStation)
+	}
+
+	if _, err := req.StationImportOptions.normalize(); err != nil {
+		return nil, err
+	}
+
+	job := ImportJobModel{
+		Request: req,
+	}
+
+	if err := s.jobRepo.Insert(ctx, &job); err != nil {
+		return nil, err
+	}
+
+	s.notifyImportWorkers()
+
+	return &ImportJobResponse{
+		Success: true,
+		Data:    &job,
+	}, nil
+}
+
+// ---------------------------------- Get Import Job -------------------------
+func (s *stationServiceType) GetImportJob(ctx context.Context, id primitive.ObjectID) (*ImportJobResponse, error) {
+	job, err := s.jobRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImportJobResponse{
+		Success: true,
+		Data:    job,
+	}, nil
+}
+
+// ---------------------------------- Import Report CSV -------------------------
+func (s *stationServiceType) WriteImportReportCSV(ctx context.Context, id primitive.ObjectID, w io.Writer) error {
+	if _, err := s.jobRepo.FindByID(ctx, id); err != nil {
+		return err
+	}
+
+	// BOM so spreadsheet tools read the Thai station names as UTF-8
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"index", "station_id", "field", "raw_value", "reason", "action"}); err != nil {
+		return err
+	}
+
+	err := s.reportRepo.EachByJob(ctx, id, func(issue StationImportIssue) error {
+		return writer.Write([]string{
+			strconv.Itoa(issue.Index),
+			strconv.Itoa(issue.StationID),
+			issue.Field,
+			issue.RawValue,
+			issue.Reason,
+			issue.Action,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ---------------------------------- Import Workers -------------------------
+
+// StartImportWorkers runs the workers until ctx is cancelled. Jobs left
+// running by an instance that stopped are claimed again once their lease
+// expires.
+func (s *stationServiceType) StartImportWorkers(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go s.runImportWorker(ctx)
+	}
+
+	s.notifyImportWorkers()
+}
+
+// newImportWorkerID names this process as the owner of the jobs it claims.
+func newImportWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), primitive.NewObjectID().Hex())
+}
+
+func (s *stationServiceType) notifyImportWorkers() {
+	select {
+	case s.jobSignal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *stationServiceType) runImportWorker(ctx context.Context) {
+	for {
+		for ctx.Err() == nil {
+			job, err := s.jobRepo.ClaimNext(ctx, s.workerID, importJobLease)
+			if err != nil {
+				log.Printf("Import worker: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+
+			s.processImportJob(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.jobSignal:
+		case <-time.After(importJobPollInterval):
+		}
+	}
+}
+
+func (s *stationServiceType) processImportJob(ctx context.Context, job *ImportJobModel) {
+	if job.Attempts > 1 {
+		log.Printf("Import job %s resumed (attempt %d): %s", job.ID.Hex(), job.Attempts, job.Request.URL)
+	} else {
+		log.Printf("Import job %s started: %s", job.ID.Hex(), job.Request.URL)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.renewImportJobLease(jobCtx, cancel, job.ID)
+
+	jobCtx = withStationChange(jobCtx, "import", &job.ID)
+
+	onProgress := func(progress StationImportResponse) {
+		if err := s.jobRepo.UpdateProgress(jobCtx, job.ID, progress); err != nil {
+			log.Printf("Import job %s: %v", job.ID.Hex(), err)
+		}
+	}
+
+	// A retried job starts its report again rather than adding a second copy
+	var result *StationImportResponse
+	err := s.reportRepo.DeleteByJob(jobCtx, job.ID)
+	if err == nil {
+		result, err = s.importFromURL(jobCtx, job.Request, onProgress)
+	}
+
+	// Shutting down: hand the job straight back rather than wait for the
+	// lease to run out
+	if ctx.Err() != nil {
+		releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer releaseCancel()
+
+		if err := s.jobRepo.Release(releaseCtx, job.ID, s.workerID); err != nil {
+			log.Printf("Import job %s: %v", job.ID.Hex(), err)
+			return
+		}
+		log.Printf("Import job %s interrupted and returned to the queue", job.ID.Hex())
+		return
+	}
+	if jobCtx.Err() != nil {
+		log.Printf("Import job %s stopped: %v", job.ID.Hex(), ErrImportJobLeaseLost)
+		return
+	}
+
+	if result == nil {
+		result = &StationImportResponse{}
+	}
+	if err != nil {
+		result.Success = false
+		result.Message = "Import failed"
+	}
+
+	if err := s.jobRepo.Finish(jobCtx, job.ID, s.workerID, *result, err); err != nil {
+		log.Printf("Import job %s: %v", job.ID.Hex(), err)
+		return
+	}
+
+	log.Printf("Import job %s finished: %s", job.ID.Hex(), result.Message)
+}
+
+// renewImportJobLease keeps the job's lease alive while it runs and cancels
+// the job when another instance has taken it over.
+func (s *stationServiceType) renewImportJobLease(ctx context.Context, cancel context.CancelFunc, id primitive.ObjectID) {
+	ticker := time.NewTicker(importJobLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.jobRepo.RenewLease(ctx, id, s.workerID, importJobLease)
+		if errors.Is(err, ErrImportJobLeaseLost) {
+			cancel()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Import job %s: %v", id.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/zombox0633/go_spinsoft/src/georules"
+)
+
+// ---------------------------------- Validate Stations -------------------------
+
+// ValidateStations runs the import checks and geo rules on parsed stations
+// without writing anything, and reports every issue rather than a preview.
+func (s *stationServiceType) ValidateStations(stations []StationModel) *StationValidationResponse {
+	result := &StationValidationResponse{
+		TotalCount:   len(stations),
+		DuplicateIDs: []int{},
+		Report:       []StationImportIssue{},
+	}
+
+	seen := make(map[int]int, len(stations))
+	for index, station := range stations {
+		station.applyGeoRules(s.geoRules)
+
+		for _, issue := range station.Issues {
+			issue.Index = index
+			result.Report = append(result.Report, issue)
+		}
+
+		if station.IsRejected() {
+			result.RejectedCount++
+			continue
+		}
+
+		if station.WasInvalidated {
+			result.InvalidCoordinates++
+		}
+
+		seen[station.StationID]++
+		if seen[station.StationID] == 2 {
+			result.DuplicateIDs = append(result.DuplicateIDs, station.StationID)
+		}
+
+		result.ValidCount++
+	}
+
+	sort.Ints(result.DuplicateIDs)
+	result.Success = result.RejectedCount == 0 && len(result.DuplicateIDs) == 0
+
+	return result
+}
+
+// ---------------------------------- Reindex Stations -------------------------
+
+// ReindexStations rebuilds the location point the geo queries use from each
+// station's lat/long, for stations edited outside the service. Every rebuilt
+// location is recorded in the station's history.
+func (s *stationServiceType) ReindexStations(ctx context.Context) (*StationReindexResponse, error) {
+	stations, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stations reindexed before a failure are already saved
+	defer s.mapCache.invalidate()
+
+	result := &StationReindexResponse{}
+	for i := range stations {
+		station := &stations[i]
+		if station.DeletedAt != nil {
+			continue
+		}
+		result.TotalCount++
+
+		location := s.indexedLocation(*station)
+		if sameLocation(station.Location, location) {
+			continue
+		}
+
+		before := *station
+		station.Location = location
+		if err := s.repo.Update(ctx, station); err != nil {
+			return nil, fmt.Errorf("failed to reindex station %d: %w", station.StationID, err)
+		}
+		result.UpdatedCount++
+
+		if err := s.recordStationChange(ctx, StationChangeUpdate, &before, *station); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Reindexed %d stations, %d updated", result.TotalCount, result.UpdatedCount)
+	return result, nil
+}
+
+// indexedLocation is the point a station should have. Stations with bad
+// coordinates, or ones a geo rule rejects or deactivates, stay out of the index.
+func (s *stationServiceType) indexedLocation(station StationModel) *GeoJSONPointModel {
+	if station.coordinateError() != "" {
+		return nil
+	}
+
+	if station.Location == nil {
+		for _, violation := range s.geoRules.Evaluate(station.Lat, station.Long) {
+			if violation.Action != georules.ActionWarn {
+				return nil
+			}
+		}
+	}
+
+	station.syncLocation()
+	return station.Location
+}
+
+func sameLocation(a, b *GeoJSONPointModel) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || len(a.Coordinates) != len(b.Coordinates) {
+		return false
+	}
+	for i := range a.Coordinates {
+		if a.Coordinates[i] != b.Coordinates[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,487 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// nearestMaxDistanceKm matches the $geoNear maxDistance of FindNearestStation.
+const nearestMaxDistanceKm = 10
+
+// memoryStationRepositoryType keeps every station in memory with a KD-tree
+// over the ones the geo queries can return. It is loaded from a JSON snapshot
+// and changes are not written back, which suits development and tests.
+type memoryStationRepositoryType struct {
+	mu       sync.RWMutex
+	stations map[int]*StationModel
+	index    *stationIndex
+}
+
+// NewMemoryStationRepository loads a snapshot read by ReadStationSnapshot. An
+// empty path starts with no stations.
+func NewMemoryStationRepository(snapshotFile string) (StationRepository, error) {
+	r := &memoryStationRepositoryType{
+		stations: make(map[int]*StationModel),
+	}
+
+	if snapshotFile != "" {
+		stations, err := ReadStationSnapshot(snapshotFile)
+		if err != nil {
+			return nil, err
+		}
+
+		now := primitive.NewDateTimeFromTime(time.Now())
+		for i := range stations {
+			stations[i].ID = primitive.NewObjectID()
+			stations[i].CreatedAt = now
+			stations[i].UpdatedAt = now
+			r.stations[stations[i].StationID] = &stations[i]
+		}
+	}
+
+	r.reindex()
+	return r, nil
+}
+
+// reindex must be called with mu held for writing.
+func (r *memoryStationRepositoryType) reindex() {
+	entries := make([]indexEntry, 0, len(r.stations))
+	for _, station := range r.stations {
+		if !isGeoCandidate(station) {
+			continue
+		}
+
+		entries = append(entries, indexEntry{
+			point:     unitVector(station.Location.Coordinates[1], station.Location.Coordinates[0]),
+			stationID: station.StationID,
+		})
+	}
+
+	r.index = newStationIndex(entries)
+}
+
+// isGeoCandidate applies the filter every Mongo geo query uses.
+func isGeoCandidate(station *StationModel) bool {
+	return station.Active == 1 && isMapCandidate(station)
+}
+
+// isMapCandidate keeps every located station, active or not.
+func isMapCandidate(station *StationModel) bool {
+	return station.DeletedAt == nil &&
+		station.Location != nil && len(station.Location.Coordinates) >= 2
+}
+
+// sorted must be called with mu held.
+func (r *memoryStationRepositoryType) sorted(keep func(*StationModel) bool) []*StationModel {
+	stations := make([]*StationModel, 0, len(r.stations))
+	for _, station := range r.stations {
+		if keep == nil || keep(station) {
+			stations = append(stations, station)
+		}
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].StationID < stations[j].StationID
+	})
+	return stations
+}
+
+func cloneStation(station *StationModel) StationModel {
+	clone := *station
+	if station.Location != nil {
+		location := *station.Location
+		location.Coordinates = append([]float64(nil), station.Location.Coordinates...)
+		clone.Location = &location
+	}
+	if station.DeletedAt != nil {
+		deletedAt := *station.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+	return clone
+}
+
+// setStationFields copies what stationSetFields writes in Mongo.
+func setStationFields(target *StationModel, station StationModel, now primitive.DateTime) {
+	id, objectID, createdAt, deletedAt := target.StationID, target.ID, target.CreatedAt, target.DeletedAt
+
+	*target = cloneStation(&station)
+	target.StationID = id
+	target.ID = objectID
+	target.CreatedAt = createdAt
+	target.DeletedAt = deletedAt
+	target.UpdatedAt = now
+	target.WasInvalidated = false
+	target.InvalidReason = ""
+	target.Issues = nil
+}
+
+// ReadStationSnapshot reads a file in the station import JSON format for the
+// local stores. Records the importer would reject are skipped.
+func ReadStationSnapshot(path string) ([]StationModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open station snapshot: %w", err)
+	}
+	defer file.Close()
+
+	var stations []StationModel
+	err = (&jsonStationParser{}).Stream(file, func(station StationModel) error {
+		if !station.IsRejected() {
+			station.Issues = nil
+			stations = append(stations, station)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load station snapshot: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Upsert Many -------------------------
+func (r *memoryStationRepositoryType) UpsertMany(ctx context.Context, stations []StationModel) (*StationUpsertResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	result := &StationUpsertResult{}
+
+	for _, station := range stations {
+		existing, ok := r.stations[station.StationID]
+		if !ok {
+			existing = &StationModel{
+				ID:        primitive.NewObjectID(),
+				StationID: station.StationID,
+				CreatedAt: now,
+			}
+			r.stations[station.StationID] = existing
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+
+		setStationFields(existing, station, now)
+		existing.DeletedAt = nil
+	}
+
+	r.reindex()
+	return result, nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *memoryStationRepositoryType) FindByID(ctx context.Context, stationID int) (*StationModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	station, ok := r.stations[stationID]
+	if !ok {
+		return nil, ErrStationNotFound
+	}
+
+	clone := cloneStation(station)
+	return &clone, nil
+}
+
+// ---------------------------------- Find By IDs -------------------------
+func (r *memoryStationRepositoryType) FindByIDs(ctx context.Context, stationIDs []int) ([]StationModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stations []StationModel
+	seen := make(map[int]bool, len(stationIDs))
+	for _, stationID := range stationIDs {
+		station, ok := r.stations[stationID]
+		if !ok || seen[stationID] {
+			continue
+		}
+		seen[stationID] = true
+		stations = append(stations, cloneStation(station))
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *memoryStationRepositoryType) Insert(ctx context.Context, station *StationModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.stations[station.StationID]; ok {
+		return ErrStationExists
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	station.ID = primitive.NewObjectID()
+	station.CreatedAt = now
+	station.UpdatedAt = now
+
+	stored := cloneStation(station)
+	stored.WasInvalidated = false
+	stored.InvalidReason = ""
+	stored.Issues = nil
+	r.stations[station.StationID] = &stored
+
+	r.reindex()
+	return nil
+}
+
+// ---------------------------------- Update -------------------------
+func (r *memoryStationRepositoryType) Update(ctx context.Context, station *StationModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.stations[station.StationID]
+	if !ok {
+		return ErrStationNotFound
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	setStationFields(existing, *station, now)
+
+	station.UpdatedAt = now
+	r.reindex()
+	return nil
+}
+
+// ---------------------------------- Delete -------------------------
+func (r *memoryStationRepositoryType) Delete(ctx context.Context, stationID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.stations[stationID]; !ok {
+		return ErrStationNotFound
+	}
+
+	delete(r.stations, stationID)
+	r.reindex()
+	return nil
+}
+
+// ---------------------------------- Find Search Candidates -------------------------
+func (r *memoryStationRepositoryType) FindSearchCandidates(ctx context.Context) ([]StationModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stations []StationModel
+	for _, station := range r.sorted(func(station *StationModel) bool {
+		return station.Active == 1 && station.DeletedAt == nil
+	}) {
+		stations = append(stations, cloneStation(station))
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Find All -------------------------
+func (r *memoryStationRepositoryType) FindAll(ctx context.Context) ([]StationModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stations []StationModel
+	for _, station := range r.sorted(nil) {
+		stations = append(stations, cloneStation(station))
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Find All IDs -------------------------
+func (r *memoryStationRepositoryType) FindAllIDs(ctx context.Context) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stationIDs := make([]int, 0, len(r.stations))
+	for _, station := range r.sorted(func(station *StationModel) bool {
+		return station.DeletedAt == nil
+	}) {
+		stationIDs = append(stationIDs, station.StationID)
+	}
+
+	return stationIDs, nil
+}
+
+// ---------------------------------- Deactivate Many -------------------------
+func (r *memoryStationRepositoryType) DeactivateMany(ctx context.Context, stationIDs []int, comment string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, stationID := range stationIDs {
+		station, ok := r.stations[stationID]
+		if !ok || station.Active == 0 {
+			continue
+		}
+
+		station.Active = 0
+		station.UpdatedAt = now
+		if station.Comment == "" || station.Comment == "NULL" {
+			station.Comment = comment
+		} else {
+			station.Comment = fmt.Sprintf("New Comment: %s | Original Comment: %s", comment, station.Comment)
+		}
+	}
+
+	r.reindex()
+	return nil
+}
+
+// ---------------------------------- Soft Delete Many -------------------------
+func (r *memoryStationRepositoryType) SoftDeleteMany(ctx context.Context, stationIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, stationID := range stationIDs {
+		station, ok := r.stations[stationID]
+		if !ok {
+			continue
+		}
+
+		deletedAt := now
+		station.DeletedAt = &deletedAt
+		station.UpdatedAt = now
+	}
+
+	r.reindex()
+	return nil
+}
+
+// ---------------------------------- Find Nearest Station -------------------------
+func (r *memoryStationRepositoryType) FindNearestStation(ctx context.Context, data NearestStationRequest) ([]NearestStationData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	neighbours := r.index.nearest(data.Lat, data.Long, data.Limit, nearestMaxDistanceKm)
+	if len(neighbours) == 0 {
+		return nil, ErrNoStationsNearby
+	}
+
+	return r.nearestData(data.Lat, data.Long, neighbours), nil
+}
+
+// ---------------------------------- Find Nearest Station Pagination -------------------------
+func (r *memoryStationRepositoryType) FindNearestStationPagination(ctx context.Context, data NearestStationPaginationRequest) ([]NearestStationData, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totalItems := r.index.Len()
+	if totalItems == 0 {
+		return nil, 0, fmt.Errorf("no results returned")
+	}
+
+	start := (data.Page - 1) * data.PageSize
+	neighbours := r.index.nearest(data.Lat, data.Long, start+data.PageSize, 0)
+	if start >= len(neighbours) {
+		return []NearestStationData{}, totalItems, nil
+	}
+
+	return r.nearestData(data.Lat, data.Long, neighbours[start:]), totalItems, nil
+}
+
+// nearestData must be called with mu held. Distances are rounded the same way
+// as the $geoNear results.
+func (r *memoryStationRepositoryType) nearestData(lat, long float64, neighbours []indexNeighbour) []NearestStationData {
+	responses := make([]NearestStationData, len(neighbours))
+	for i, neighbour := range neighbours {
+		station := r.stations[neighbour.stationID]
+		distance := utils.HaversineKm(lat, long, station.Location.Coordinates[1], station.Location.Coordinates[0])
+
+		responses[i] = NearestStationData{
+			ID:       station.StationID,
+			Name:     station.Name,
+			EnName:   station.EnName,
+			Lat:      station.Lat,
+			Long:     station.Long,
+			Distance: math.Round(distance*1000) / 1000,
+		}
+	}
+
+	return responses
+}
+
+// ---------------------------------- Find Within -------------------------
+func (r *memoryStationRepositoryType) FindWithin(ctx context.Context, data StationWithinRequest) ([]StationWithinData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	responses := make([]StationWithinData, 0)
+	for _, station := range r.sorted(isGeoCandidate) {
+		if !data.shape.contains(station.Location.Coordinates[1], station.Location.Coordinates[0]) {
+			continue
+		}
+
+		responses = append(responses, StationWithinData{
+			ID:     station.StationID,
+			Name:   station.Name,
+			EnName: station.EnName,
+			Lat:    station.Lat,
+			Long:   station.Long,
+		})
+		if len(responses) > data.Limit {
+			break
+		}
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Map Points -------------------------
+func (r *memoryStationRepositoryType) FindMapPoints(ctx context.Context) ([]StationMapPoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var points []StationMapPoint
+	for _, station := range r.sorted(isMapCandidate) {
+		clone := cloneStation(station)
+		points = append(points, StationMapPoint{
+			StationID: clone.StationID,
+			Name:      clone.Name,
+			EnName:    clone.EnName,
+			Lat:       clone.Lat,
+			Long:      clone.Long,
+			Class:     clone.Class,
+			Active:    clone.Active,
+			DualTrack: clone.DualTrack,
+			Location:  clone.Location,
+		})
+	}
+
+	return points, nil
+}
+
+// ---------------------------------- Each Export Station -------------------------
+
+// EachExportStation works on a copy so fn can take as long as it needs without
+// holding up writers.
+func (r *memoryStationRepositoryType) EachExportStation(ctx context.Context, data StationExportRequest, fn func(StationModel) error) error {
+	r.mu.RLock()
+	var stations []StationModel
+	for _, station := range r.sorted(isGeoCandidate) {
+		if data.Lat != nil && data.Long != nil &&
+			utils.HaversineKm(*data.Lat, *data.Long, station.Location.Coordinates[1], station.Location.Coordinates[0]) > data.RadiusKm {
+			continue
+		}
+		stations = append(stations, cloneStation(station))
+	}
+	r.mu.RUnlock()
+
+	for _, station := range stations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(station); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
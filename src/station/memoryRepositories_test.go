@@ -0,0 +1,225 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const fixtureStationsFile = "testdata/stations.json"
+
+// nearestQueries cover the dense Bangkok fixtures, a point between two
+// clusters and one with nothing within 10 km.
+var nearestQueries = []struct {
+	name      string
+	lat, long float64
+}{
+	{"bangkok", 13.7466, 100.5393},
+	{"bang sue", 13.8000, 100.5400},
+	{"don mueang", 13.9100, 100.6000},
+	{"chiang mai", 18.7000, 99.0100},
+	{"gulf", 12.9000, 100.9000},
+}
+
+func loadFixtureStations(t *testing.T) []StationModel {
+	t.Helper()
+
+	stations, err := ReadStationSnapshot(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("ReadStationSnapshot: %v", err)
+	}
+	return stations
+}
+
+// referenceNearest is what $geoNear returns: active stations with a location
+// ordered by spherical distance.
+func referenceNearest(stations []StationModel, lat, long, maxKm float64) []NearestStationData {
+	var results []NearestStationData
+	for i := range stations {
+		station := &stations[i]
+		if !isGeoCandidate(station) {
+			continue
+		}
+
+		distance := utils.HaversineKm(lat, long, station.Lat, station.Long)
+		if maxKm > 0 && distance > maxKm {
+			continue
+		}
+
+		results = append(results, NearestStationData{
+			ID:       station.StationID,
+			Name:     station.Name,
+			EnName:   station.EnName,
+			Lat:      station.Lat,
+			Long:     station.Long,
+			Distance: distance,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Distance < results[j].Distance
+	})
+	for i := range results {
+		results[i].Distance = math.Round(results[i].Distance*1000) / 1000
+	}
+	return results
+}
+
+// assertSameNearest allows 2 m for rounding plus relTolerance of the distance,
+// for backends that measure on a different earth model.
+func assertSameNearest(t *testing.T, label string, got, want []NearestStationData, relTolerance float64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d stations, want %d\ngot:  %v\nwant: %v", label, len(got), len(want), got, want)
+	}
+
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Name != want[i].Name || got[i].EnName != want[i].EnName {
+			t.Errorf("%s[%d]: got station %d %q, want %d %q", label, i, got[i].ID, got[i].EnName, want[i].ID, want[i].EnName)
+		}
+		if math.Abs(got[i].Distance-want[i].Distance) > 0.002+relTolerance*want[i].Distance {
+			t.Errorf("%s[%d]: station %d distance %v km, want %v km", label, i, got[i].ID, got[i].Distance, want[i].Distance)
+		}
+	}
+}
+
+func TestMemoryFindNearestStation(t *testing.T) {
+	ctx := context.Background()
+	stations := loadFixtureStations(t)
+
+	repo, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+
+	for _, query := range nearestQueries {
+		for _, limit := range []int{1, 3, 100} {
+			label := fmt.Sprintf("%s limit %d", query.name, limit)
+			want := referenceNearest(stations, query.lat, query.long, nearestMaxDistanceKm)
+			if len(want) > limit {
+				want = want[:limit]
+			}
+
+			got, err := repo.FindNearestStation(ctx, NearestStationRequest{Lat: query.lat, Long: query.long, Limit: limit})
+			if len(want) == 0 {
+				if !errors.Is(err, ErrNoStationsNearby) {
+					t.Errorf("%s: got %v, %v, want ErrNoStationsNearby", label, got, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", label, err)
+			}
+
+			assertSameNearest(t, label, got, want, 0)
+		}
+	}
+}
+
+func TestMemoryFindNearestStationPagination(t *testing.T) {
+	ctx := context.Background()
+	stations := loadFixtureStations(t)
+
+	repo, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+
+	const pageSize = 4
+	for _, query := range nearestQueries {
+		want := referenceNearest(stations, query.lat, query.long, 0)
+
+		var got []NearestStationData
+		for page := 1; page <= len(want)/pageSize+1; page++ {
+			data, total, err := repo.FindNearestStationPagination(ctx, NearestStationPaginationRequest{
+				Lat: query.lat, Long: query.long, Page: page, PageSize: pageSize,
+			})
+			if err != nil {
+				t.Fatalf("%s page %d: %v", query.name, page, err)
+			}
+			if total != len(want) {
+				t.Errorf("%s page %d: total %d, want %d", query.name, page, total, len(want))
+			}
+			got = append(got, data...)
+		}
+
+		assertSameNearest(t, query.name, got, want, 0)
+	}
+}
+
+// TestMemoryNearestMatchesMongo runs the same queries against a scratch
+// database when MONGO_URI is set.
+func TestMemoryNearestMatchesMongo(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+
+	database := client.Database(fmt.Sprintf("go_spinsoft_test_%d", time.Now().UnixNano()))
+	defer database.Drop(context.Background())
+
+	collection := database.Collection("stations")
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "location", Value: "2dsphere"}},
+	})
+	if err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	mongoRepo := NewStationRepository(collection)
+	if _, err := mongoRepo.UpsertMany(ctx, loadFixtureStations(t)); err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+
+	memoryRepo, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+
+	for _, query := range nearestQueries {
+		req := NearestStationRequest{Lat: query.lat, Long: query.long, Limit: 5}
+		want, mongoErr := mongoRepo.FindNearestStation(ctx, req)
+		got, memoryErr := memoryRepo.FindNearestStation(ctx, req)
+		if (mongoErr == nil) != (memoryErr == nil) {
+			t.Fatalf("%s: mongo error %v, memory error %v", query.name, mongoErr, memoryErr)
+		}
+		assertSameNearest(t, query.name+" nearest", got, want, 0)
+
+		for page := 1; page <= 4; page++ {
+			req := NearestStationPaginationRequest{Lat: query.lat, Long: query.long, Page: page, PageSize: 5}
+			want, wantTotal, err := mongoRepo.FindNearestStationPagination(ctx, req)
+			if err != nil {
+				t.Fatalf("%s page %d mongo: %v", query.name, page, err)
+			}
+			got, gotTotal, err := memoryRepo.FindNearestStationPagination(ctx, req)
+			if err != nil {
+				t.Fatalf("%s page %d memory: %v", query.name, page, err)
+			}
+
+			if gotTotal != wantTotal {
+				t.Errorf("%s page %d: total %d, mongo %d", query.name, page, gotTotal, wantTotal)
+			}
+			assertSameNearest(t, fmt.Sprintf("%s page %d", query.name, page), got, want, 0)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package station
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var sqlMigrations embed.FS
+
+type sqlMigration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadSQLMigrations reads the files of one dialect in version order. File
+// names start with the version, as in 0001_create_stations.sql.
+func loadSQLMigrations(dialect string) ([]sqlMigration, error) {
+	files, err := fs.Glob(sqlMigrations, path.Join("migrations", dialect, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]sqlMigration, 0, len(files))
+	for _, file := range files {
+		name := path.Base(file)
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: file name must start with a version number", name)
+		}
+
+		sql, err := sqlMigrations.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, sqlMigration{Version: version, Name: name, SQL: string(sql)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
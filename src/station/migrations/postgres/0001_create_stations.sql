@@ -0,0 +1,29 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS stations (
+    id              integer PRIMARY KEY,
+    object_id       char(24) NOT NULL,
+    station_code    integer NOT NULL DEFAULT 0,
+    name            text NOT NULL DEFAULT '',
+    en_name         text NOT NULL DEFAULT '',
+    th_short        text NOT NULL DEFAULT '',
+    en_short        text NOT NULL DEFAULT '',
+    chname          text NOT NULL DEFAULT '',
+    controldivision integer NOT NULL DEFAULT 0,
+    exact_km        integer NOT NULL DEFAULT 0,
+    exact_distance  integer NOT NULL DEFAULT 0,
+    km              integer NOT NULL DEFAULT 0,
+    class           integer NOT NULL DEFAULT 0,
+    lat             double precision NOT NULL DEFAULT 0,
+    long            double precision NOT NULL DEFAULT 0,
+    location        geography(Point, 4326),
+    active          integer NOT NULL DEFAULT 0,
+    giveway         integer NOT NULL DEFAULT 0,
+    dual_track      integer NOT NULL DEFAULT 0,
+    comment         text NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    updated_at      timestamptz NOT NULL DEFAULT now(),
+    deleted_at      timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS stations_active_idx ON stations (active) WHERE deleted_at IS NULL;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS stations_location_gist ON stations USING GIST (location);
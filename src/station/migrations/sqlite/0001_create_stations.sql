@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS stations (
+    id              INTEGER PRIMARY KEY,
+    object_id       TEXT NOT NULL,
+    station_code    INTEGER NOT NULL DEFAULT 0,
+    name            TEXT NOT NULL DEFAULT '',
+    en_name         TEXT NOT NULL DEFAULT '',
+    th_short        TEXT NOT NULL DEFAULT '',
+    en_short        TEXT NOT NULL DEFAULT '',
+    chname          TEXT NOT NULL DEFAULT '',
+    controldivision INTEGER NOT NULL DEFAULT 0,
+    exact_km        INTEGER NOT NULL DEFAULT 0,
+    exact_distance  INTEGER NOT NULL DEFAULT 0,
+    km              INTEGER NOT NULL DEFAULT 0,
+    class           INTEGER NOT NULL DEFAULT 0,
+    lat             REAL NOT NULL DEFAULT 0,
+    long            REAL NOT NULL DEFAULT 0,
+    location_long   REAL,
+    location_lat    REAL,
+    active          INTEGER NOT NULL DEFAULT 0,
+    giveway         INTEGER NOT NULL DEFAULT 0,
+    dual_track      INTEGER NOT NULL DEFAULT 0,
+    comment         TEXT NOT NULL DEFAULT '',
+    created_at      INTEGER NOT NULL,
+    updated_at      INTEGER NOT NULL,
+    deleted_at      INTEGER
+);
+
+-- Only stations the geo queries can return are indexed
+CREATE VIRTUAL TABLE IF NOT EXISTS stations_rtree USING rtree(id, min_long, max_long, min_lat, max_lat);
+
+CREATE TRIGGER IF NOT EXISTS stations_rtree_insert AFTER INSERT ON stations
+WHEN NEW.active = 1 AND NEW.location_long IS NOT NULL AND NEW.deleted_at IS NULL
+BEGIN
+    INSERT INTO stations_rtree VALUES (NEW.id, NEW.location_long, NEW.location_long, NEW.location_lat, NEW.location_lat);
+END;
+
+CREATE TRIGGER IF NOT EXISTS stations_rtree_update AFTER UPDATE ON stations
+BEGIN
+    DELETE FROM stations_rtree WHERE id = OLD.id;
+    INSERT INTO stations_rtree
+    SELECT NEW.id, NEW.location_long, NEW.location_long, NEW.location_lat, NEW.location_lat
+    WHERE NEW.active = 1 AND NEW.location_long IS NOT NULL AND NEW.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER IF NOT EXISTS stations_rtree_delete AFTER DELETE ON stations
+BEGIN
+    DELETE FROM stations_rtree WHERE id = OLD.id;
+END;
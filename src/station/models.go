@@ -3,7 +3,9 @@ package station
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/zombox0633/go_spinsoft/src/georules"
 	"github.com/zombox0633/go_spinsoft/src/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -14,38 +16,69 @@ type GeoJSONPointModel struct {
 }
 
 type StationModel struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	StationID     int                `bson:"id" json:"station_id"`
-	StationCode   int                `bson:"station_code" json:"station_code"`
-	Name          string             `bson:"name" json:"name"`
-	EnName        string             `bson:"en_name" json:"en_name"`
-	ThShort       string             `bson:"th_short" json:"th_short"`
-	EnShort       string             `bson:"en_short" json:"en_short"`
-	ChName        string             `bson:"chname" json:"chname"`
-	ControlDiv    int                `bson:"controldivision" json:"controldivision"`
-	ExactKM       int                `bson:"exact_km" json:"exact_km"`
-	ExactDistance int                `bson:"exact_distance" json:"exact_distance"`
-	KM            int                `bson:"km" json:"km"`
-	Class         int                `bson:"class" json:"class"`
-	Lat           float64            `bson:"lat" json:"lat"`
-	Long          float64            `bson:"long" json:"long"`
-	Location      *GeoJSONPointModel `bson:"location,omitempty" json:"coordinates,omitempty"`
-	Active        int                `bson:"active" json:"active"`
-	Giveway       int                `bson:"giveway" json:"giveway"`
-	DualTrack     int                `bson:"dual_track" json:"dual_track"`
-	Comment       string             `bson:"comment" json:"comment"`
-	CreatedAt     primitive.DateTime `bson:"created_at" json:"created_at"`
-	UpdatedAt     primitive.DateTime `bson:"updated_at" json:"updated_at"`
-
-	WasInvalidated bool `bson:"-" json:"-"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	StationID     int                 `bson:"id" json:"station_id"`
+	StationCode   int                 `bson:"station_code" json:"station_code"`
+	Name          string              `bson:"name" json:"name"`
+	EnName        string              `bson:"en_name" json:"en_name"`
+	ThShort       string              `bson:"th_short" json:"th_short"`
+	EnShort       string              `bson:"en_short" json:"en_short"`
+	ChName        string              `bson:"chname" json:"chname"`
+	ControlDiv    int                 `bson:"controldivision" json:"controldivision"`
+	ExactKM       int                 `bson:"exact_km" json:"exact_km"`
+	ExactDistance int                 `bson:"exact_distance" json:"exact_distance"`
+	KM            int                 `bson:"km" json:"km"`
+	Class         int                 `bson:"class" json:"class"`
+	Lat           float64             `bson:"lat" json:"lat"`
+	Long          float64             `bson:"long" json:"long"`
+	Location      *GeoJSONPointModel  `bson:"location,omitempty" json:"coordinates,omitempty"`
+	Active        int                 `bson:"active" json:"active"`
+	Giveway       int                 `bson:"giveway" json:"giveway"`
+	DualTrack     int                 `bson:"dual_track" json:"dual_track"`
+	Comment       string              `bson:"comment" json:"comment"`
+	CreatedAt     primitive.DateTime  `bson:"created_at" json:"created_at"`
+	UpdatedAt     primitive.DateTime  `bson:"updated_at" json:"updated_at"`
+	DeletedAt     *primitive.DateTime `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+
+	WasInvalidated bool                 `bson:"-" json:"-"`
+	InvalidReason  string               `bson:"-" json:"-"`
+	Issues         []StationImportIssue `bson:"-" json:"-"`
 }
 
+const (
+	IssueActionRejected = "rejected"
+	IssueActionModified = "modified"
+	IssueActionWarned   = "warned"
+)
+
+type StationImportIssue struct {
+	JobID     *primitive.ObjectID `bson:"job_id,omitempty" json:"-"`
+	Index     int                 `bson:"index" json:"index"`
+	StationID int                 `bson:"station_id" json:"station_id"`
+	Field     string              `bson:"field" json:"field"`
+	RawValue  string              `bson:"raw_value" json:"raw_value"`
+	Reason    string              `bson:"reason" json:"reason"`
+	Action    string              `bson:"action" json:"action"`
+}
+
+var (
+	stationIntFields   = []string{"id", "station_code", "controldivision", "exact_km", "exact_distance", "km", "class", "active", "giveway", "dual_track"}
+	stationFloatFields = []string{"lat", "long"}
+)
+
 func (s *StationModel) UnmarshalJSON(data []byte) error {
 	var raw map[string]interface{}
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
 
+	s.fromRaw(raw)
+	return nil
+}
+
+func (s *StationModel) fromRaw(raw map[string]interface{}) {
+	s.Issues = nil
+
 	s.StationID = utils.ToInt(raw["id"])
 	s.StationCode = utils.ToInt(raw["station_code"])
 	s.Name = utils.ToString(raw["name"])
@@ -66,42 +99,141 @@ func (s *StationModel) UnmarshalJSON(data []byte) error {
 	s.DualTrack = utils.ToInt(raw["dual_track"])
 	s.Comment = utils.ToString(raw["comment"])
 
-	isValidCoordinates := true
-	var invalidReason string
+	for _, field := range stationIntFields {
+		if !utils.IsInt(raw[field]) {
+			// Fractions are truncated, anything else falls back to 0
+			reason := fmt.Sprintf("not an integer, stored as %d", utils.ToInt(raw[field]))
+			s.addIssue(field, raw[field], reason, IssueActionModified)
+		}
+	}
+
+	for _, field := range stationFloatFields {
+		if !utils.IsFloat(raw[field]) {
+			s.addIssue(field, raw[field], "not a number, stored as 0", IssueActionModified)
+		}
+	}
+
+	if s.StationID <= 0 {
+		s.addIssue("id", raw["id"], "missing or invalid station id", IssueActionRejected)
+	}
+
+	if reason := s.coordinateError(); reason != "" {
+		s.invalidate(reason)
+		s.addIssue("lat,long", fmt.Sprintf("%v,%v", rawValue(raw["lat"]), rawValue(raw["long"])),
+			reason+"; station deactivated", IssueActionModified)
+	} else {
+		s.syncLocation()
+	}
+}
+
+func (s *StationModel) addIssue(field string, value interface{}, reason, action string) {
+	s.Issues = append(s.Issues, StationImportIssue{
+		StationID: s.StationID,
+		Field:     field,
+		RawValue:  rawValue(value),
+		Reason:    reason,
+		Action:    action,
+	})
+}
+
+// IsRejected reports whether the record cannot be imported at all.
+func (s *StationModel) IsRejected() bool {
+	for _, issue := range s.Issues {
+		if issue.Action == IssueActionRejected {
+			return true
+		}
+	}
+	return false
+}
+
+func rawValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (s *StationModel) coordinateError() string {
+	if s.Lat == 0 && s.Long == 0 {
+		return "Coordinates are zero (0, 0)"
+	}
+
+	var reasons []string
 
 	if s.Lat < -90 || s.Lat > 90 {
-		isValidCoordinates = false
-		invalidReason = fmt.Sprintf("Invalid latitude: must be between -90 and 90")
+		reasons = append(reasons, "Invalid latitude: must be between -90 and 90")
 	}
 
-	if s.Long < -180 || s.Lat > 180 {
-		isValidCoordinates = false
-		invalidReason += fmt.Sprintf("Invalid longitude: must be between -180 and 180")
+	if s.Long < -180 || s.Long > 180 {
+		reasons = append(reasons, "Invalid longitude: must be between -180 and 180")
 	}
 
+	return strings.Join(reasons, "; ")
+}
+
+// applyGeoRules runs the configured geographic rules. Points the bounds check
+// already invalidated only reach the swapped rule, and its reason replaces the
+// bounds error because it says what is actually wrong with the row.
+func (s *StationModel) applyGeoRules(rules *georules.Engine) {
 	if s.Lat == 0 && s.Long == 0 {
-		isValidCoordinates = false
-		invalidReason = "Coordinates are zero (0, 0)"
+		return
 	}
 
-	if !isValidCoordinates {
-		s.Active = 0
-		s.WasInvalidated = true
+	coordinates := fmt.Sprintf("%v,%v", s.Lat, s.Long)
+	outOfRange := s.coordinateError() != ""
 
-		if s.Comment == "" || s.Comment == "NULL" {
-			s.Comment = fmt.Sprintf(invalidReason)
-		} else {
-			s.Comment = fmt.Sprintf("New Comment: %s | Original Comment: %s", invalidReason, s.Comment)
+	for _, violation := range rules.Evaluate(s.Lat, s.Long) {
+		reason := fmt.Sprintf("%s: %s", violation.Rule, violation.Reason)
+
+		if outOfRange && violation.Action != georules.ActionReject {
+			s.replaceInvalidReason(violation.Reason, reason+"; station deactivated")
+			continue
 		}
 
-		s.Location = nil
-		fmt.Printf("Invalid coordinates for station: id %v - %s - %s\n", s.StationID, s.Name, invalidReason)
-	} else {
-		s.Location = &GeoJSONPointModel{
-			Type:        "Point",
-			Coordinates: []float64{s.Long, s.Lat}, // x, y
+		switch violation.Action {
+		case georules.ActionReject:
+			s.addIssue("lat,long", coordinates, reason, IssueActionRejected)
+		case georules.ActionDeactivate:
+			if !s.WasInvalidated {
+				s.invalidate(violation.Reason)
+			}
+			s.addIssue("lat,long", coordinates, reason+"; station deactivated", IssueActionModified)
+		case georules.ActionWarn:
+			s.addIssue("lat,long", coordinates, reason, IssueActionWarned)
+		}
+	}
+}
+
+// replaceInvalidReason swaps the reason of an earlier invalidate call, in the
+// comment and in the issue reported for it, for a more specific one.
+func (s *StationModel) replaceInvalidReason(reason, issueReason string) {
+	for i := range s.Issues {
+		if s.Issues[i].Field == "lat,long" && s.Issues[i].Reason == s.InvalidReason+"; station deactivated" {
+			s.Issues[i].Reason = issueReason
 		}
 	}
 
-	return nil
+	s.Comment = strings.Replace(s.Comment, s.InvalidReason, reason, 1)
+	s.InvalidReason = reason
+}
+
+func (s *StationModel) invalidate(reason string) {
+	s.Active = 0
+	s.WasInvalidated = true
+	s.InvalidReason = reason
+
+	if s.Comment == "" || s.Comment == "NULL" {
+		s.Comment = reason
+	} else {
+		s.Comment = fmt.Sprintf("New Comment: %s | Original Comment: %s", reason, s.Comment)
+	}
+
+	s.Location = nil
+}
+
+func (s *StationModel) syncLocation() {
+	s.Location = &GeoJSONPointModel{
+		Type:        "Point",
+		Coordinates: []float64{s.Long, s.Lat}, // x, y
+	}
 }
@@ -0,0 +1,328 @@
+package station
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	StationFormatJSON = "json"
+	StationFormatCSV  = "csv"
+	StationFormatGTFS = "gtfs"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+type StationParser interface {
+	Parse(r io.Reader) ([]StationModel, error)
+}
+
+// NewStationParser returns the parser for format. An empty format is detected
+// from the file name extension.
+func NewStationParser(format, filename string, opts StationFileImportRequest) (StationParser, error) {
+	if format == "" {
+		format = detectStationFormat(filename)
+	}
+
+	switch strings.ToLower(format) {
+	case StationFormatJSON:
+		return &jsonStationParser{}, nil
+	case StationFormatCSV:
+		return newCSVStationParser(opts.Mapping, opts.Delimiter)
+	case StationFormatGTFS:
+		return &gtfsStationParser{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+func detectStationFormat(filename string) string {
+	name := strings.ToLower(path.Base(filename))
+
+	switch {
+	case name == "stops.txt", strings.HasSuffix(name, ".zip"):
+		return StationFormatGTFS
+	case strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, ".tsv"):
+		return StationFormatCSV
+	case strings.HasSuffix(name, ".json"):
+		return StationFormatJSON
+	default:
+		return ""
+	}
+}
+
+// ---------------------------------- JSON -------------------------
+type jsonStationParser struct{}
+
+func (p *jsonStationParser) Parse(r io.Reader) ([]StationModel, error) {
+	var stations []StationModel
+
+	err := p.Stream(r, func(station StationModel) error {
+		stations = append(stations, station)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stations, nil
+}
+
+// Stream decodes a JSON array one station at a time and passes each one to fn.
+func (p *jsonStationParser) Stream(r io.Reader, fn func(StationModel) error) error {
+	decoder := json.NewDecoder(r)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("failed to parse: expected a JSON array of stations")
+	}
+
+	for index := 0; decoder.More(); index++ {
+		var station StationModel
+		if err := decoder.Decode(&station); err != nil {
+			return fmt.Errorf("failed to parse station at index %d: %w", index, err)
+		}
+
+		if err := fn(station); err != nil {
+			return err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- CSV -------------------------
+
+// stationFieldKeys are the feed keys understood by StationModel.fromRaw.
+var stationFieldKeys = []string{
+	"id",
+	"station_code",
+	"name",
+	"en_name",
+	"th_short",
+	"en_short",
+	"chname",
+	"controldivision",
+	"exact_km",
+	"exact_distance",
+	"km",
+	"class",
+	"lat",
+	"long",
+	"active",
+	"giveway",
+	"dual_track",
+	"comment",
+}
+
+type csvStationParser struct {
+	mapping   map[string]string
+	delimiter rune
+}
+
+// newCSVStationParser maps station fields to CSV column headers. Fields that
+// are not in mapping are read from a column with the same name as the field.
+func newCSVStationParser(mapping map[string]string, delimiter string) (*csvStationParser, error) {
+	columns := make(map[string]string, len(stationFieldKeys))
+	for _, field := range stationFieldKeys {
+		columns[field] = field
+	}
+
+	for field, column := range mapping {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: unknown station field %q in mapping", ErrInvalidStation, field)
+		}
+		columns[field] = column
+	}
+
+	parser := &csvStationParser{
+		mapping:   columns,
+		delimiter: ',',
+	}
+
+	if delimiter != "" {
+		if delimiter == `\t` {
+			delimiter = "\t"
+		}
+		runes := []rune(delimiter)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("%w: delimiter must be a single character", ErrInvalidStation)
+		}
+		parser.delimiter = runes[0]
+	}
+
+	return parser, nil
+}
+
+func (p *csvStationParser) Parse(r io.Reader) ([]StationModel, error) {
+	records, err := readCSVRecords(r, p.delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := records.index[p.mapping["id"]]; !ok {
+		return nil, fmt.Errorf("failed to parse: missing column %q for station id", p.mapping["id"])
+	}
+
+	stations := make([]StationModel, 0, len(records.rows))
+	for _, row := range records.rows {
+		raw := make(map[string]interface{}, len(p.mapping))
+		for field, column := range p.mapping {
+			if value, ok := records.value(row, column); ok {
+				raw[field] = value
+			}
+		}
+
+		var station StationModel
+		station.fromRaw(raw)
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- GTFS -------------------------
+type gtfsStationParser struct{}
+
+func (p *gtfsStationParser) Parse(r io.Reader) ([]StationModel, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	stops := io.Reader(bytes.NewReader(data))
+	if bytes.HasPrefix(data, []byte("PK")) {
+		stops, err = openZipEntry(data, "stops.txt")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := readCSVRecords(stops, ',')
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range []string{"stop_id", "stop_name", "stop_lat", "stop_lon"} {
+		if _, ok := records.index[column]; !ok {
+			return nil, fmt.Errorf("failed to parse: stops.txt is missing column %q", column)
+		}
+	}
+
+	stations := make([]StationModel, 0, len(records.rows))
+	for _, row := range records.rows {
+		// Platforms and entrances belong to a parent station
+		if parent, _ := records.value(row, "parent_station"); parent != "" {
+			continue
+		}
+		if locationType, _ := records.value(row, "location_type"); locationType != "" && locationType != "0" && locationType != "1" {
+			continue
+		}
+
+		raw := map[string]interface{}{
+			"active": 1,
+		}
+		for field, column := range gtfsStopColumns {
+			if value, ok := records.value(row, column); ok {
+				raw[field] = value
+			}
+		}
+
+		var station StationModel
+		station.fromRaw(raw)
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
+
+var gtfsStopColumns = map[string]string{
+	"id":           "stop_id",
+	"station_code": "stop_code",
+	"name":         "stop_name",
+	"lat":          "stop_lat",
+	"long":         "stop_lon",
+	"comment":      "stop_desc",
+}
+
+func openZipEntry(data []byte, name string) (io.Reader, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if path.Base(file.Name) != name {
+			continue
+		}
+
+		entry, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer entry.Close()
+
+		content, err := io.ReadAll(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return bytes.NewReader(content), nil
+	}
+
+	return nil, fmt.Errorf("failed to parse: %s not found in zip", name)
+}
+
+// ---------------------------------- CSV Records -------------------------
+type csvRecords struct {
+	index map[string]int
+	rows  [][]string
+}
+
+func readCSVRecords(r io.Reader, delimiter rune) (*csvRecords, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		index[strings.TrimSpace(column)] = i
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	return &csvRecords{index: index, rows: rows}, nil
+}
+
+func (c *csvRecords) value(row []string, column string) (string, bool) {
+	i, ok := c.index[column]
+	if !ok || i >= len(row) {
+		return "", false
+	}
+	return row[i], true
+}
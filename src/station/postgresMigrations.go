@@ -0,0 +1,69 @@
+package station
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MigratePostgres applies the files in migrations/postgres that are not yet
+// recorded in schema_migrations, each in its own transaction. An advisory
+// lock keeps two instances from migrating at the same time.
+func MigratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := loadSQLMigrations("postgres")
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		applied, err := applyPostgresMigration(ctx, pool, migration.Version, migration.Name, migration.SQL)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		if applied {
+			log.Printf("Applied Postgres migration %s", migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func applyPostgresMigration(ctx context.Context, pool *pgxpool.Pool, version int, name, sql string) (bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))"); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", version, name); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,484 @@
+package station
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// postgresStationRepositoryType stores stations in the PostGIS table created
+// by MigratePostgres. location is a geography, so distances are in metres on
+// the spheroid rather than on MongoDB's sphere and can differ in the last
+// digit of distance_km.
+type postgresStationRepositoryType struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStationRepository(pool *pgxpool.Pool) StationRepository {
+	return &postgresStationRepositoryType{
+		pool: pool,
+	}
+}
+
+const postgresStationColumns = `id, object_id, station_code, name, en_name, th_short, en_short, chname,
+	controldivision, exact_km, exact_distance, km, class, lat, long,
+	ST_X(location::geometry), ST_Y(location::geometry),
+	active, giveway, dual_track, comment, created_at, updated_at, deleted_at`
+
+// postgresGeoFilter is the filter every Mongo geo query uses.
+const postgresGeoFilter = `active = 1 AND ` + postgresMapFilter
+
+// postgresMapFilter keeps every located station, active or not.
+const postgresMapFilter = `location IS NOT NULL AND deleted_at IS NULL`
+
+// postgresPoint builds a geography point from $1 long and $2 lat.
+const postgresPoint = `ST_SetSRID(ST_MakePoint($1::float8, $2::float8), 4326)::geography`
+
+func scanPostgresStation(row pgx.Row) (*StationModel, error) {
+	var station StationModel
+	var objectID string
+	var x, y *float64
+	var createdAt, updatedAt time.Time
+	var deletedAt *time.Time
+
+	err := row.Scan(
+		&station.StationID, &objectID, &station.StationCode, &station.Name, &station.EnName,
+		&station.ThShort, &station.EnShort, &station.ChName,
+		&station.ControlDiv, &station.ExactKM, &station.ExactDistance, &station.KM, &station.Class,
+		&station.Lat, &station.Long, &x, &y,
+		&station.Active, &station.Giveway, &station.DualTrack, &station.Comment,
+		&createdAt, &updatedAt, &deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	station.ID, _ = primitive.ObjectIDFromHex(objectID)
+	if x != nil && y != nil {
+		station.Location = &GeoJSONPointModel{Type: "Point", Coordinates: []float64{*x, *y}}
+	}
+	station.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	station.UpdatedAt = primitive.NewDateTimeFromTime(updatedAt)
+	if deletedAt != nil {
+		deleted := primitive.NewDateTimeFromTime(*deletedAt)
+		station.DeletedAt = &deleted
+	}
+
+	return &station, nil
+}
+
+func collectPostgresStations(rows pgx.Rows) ([]StationModel, error) {
+	defer rows.Close()
+
+	var stations []StationModel
+	for rows.Next() {
+		station, err := scanPostgresStation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+		stations = append(stations, *station)
+	}
+
+	return stations, rows.Err()
+}
+
+// postgresLocation returns the long and lat to store, or nils for stations
+// without a location.
+func postgresLocation(station StationModel) (*float64, *float64) {
+	if station.Location == nil || len(station.Location.Coordinates) < 2 {
+		return nil, nil
+	}
+	return &station.Location.Coordinates[0], &station.Location.Coordinates[1]
+}
+
+// postgresSetArgs are the values stationSetFields writes in Mongo. They are
+// $3 to $21 in postgresSetColumns, after the id and the update time.
+func postgresSetArgs(station StationModel) []interface{} {
+	long, lat := postgresLocation(station)
+
+	return []interface{}{
+		station.StationCode, station.Name, station.EnName, station.ThShort, station.EnShort, station.ChName,
+		station.ControlDiv, station.ExactKM, station.ExactDistance, station.KM, station.Class,
+		station.Lat, station.Long, long, lat,
+		station.Active, station.Giveway, station.DualTrack, station.Comment,
+	}
+}
+
+func postgresStationArgs(station StationModel, now time.Time) []interface{} {
+	return append([]interface{}{station.StationID, now}, postgresSetArgs(station)...)
+}
+
+const postgresSetColumns = `station_code = $3, name = $4, en_name = $5, th_short = $6, en_short = $7, chname = $8,
+	controldivision = $9, exact_km = $10, exact_distance = $11, km = $12, class = $13,
+	lat = $14, long = $15, location = ST_SetSRID(ST_MakePoint($16::float8, $17::float8), 4326)::geography,
+	active = $18, giveway = $19, dual_track = $20, comment = $21, updated_at = $2`
+
+// postgresInsertStation takes postgresStationArgs followed by the object id.
+const postgresInsertStation = `INSERT INTO stations (id, created_at, updated_at,
+	station_code, name, en_name, th_short, en_short, chname,
+	controldivision, exact_km, exact_distance, km, class,
+	lat, long, location, active, giveway, dual_track, comment, object_id)
+VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+	ST_SetSRID(ST_MakePoint($16::float8, $17::float8), 4326)::geography, $18, $19, $20, $21, $22)`
+
+// ---------------------------------- Upsert Many -------------------------
+func (r *postgresStationRepositoryType) UpsertMany(ctx context.Context, stations []StationModel) (*StationUpsertResult, error) {
+	if len(stations) == 0 {
+		return &StationUpsertResult{}, nil
+	}
+
+	now := time.Now()
+
+	// xmax is 0 only on rows the statement inserted
+	query := postgresInsertStation + `
+ON CONFLICT (id) DO UPDATE SET ` + postgresSetColumns + `, deleted_at = NULL
+RETURNING xmax = 0`
+
+	batch := &pgx.Batch{}
+	for _, station := range stations {
+		batch.Queue(query, append(postgresStationArgs(station, now), primitive.NewObjectID().Hex())...)
+	}
+
+	results := r.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	result := &StationUpsertResult{}
+	for range stations {
+		var inserted bool
+		if err := results.QueryRow().Scan(&inserted); err != nil {
+			return nil, fmt.Errorf("failed to insert stations: %w", err)
+		}
+
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+	}
+
+	log.Printf("Successfully processed %d stations: %d inserted, %d updated",
+		len(stations), result.Inserted, result.Updated)
+	return result, nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *postgresStationRepositoryType) FindByID(ctx context.Context, stationID int) (*StationModel, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+postgresStationColumns+` FROM stations WHERE id = $1`, stationID)
+
+	station, err := scanPostgresStation(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station: %w", err)
+	}
+
+	return station, nil
+}
+
+// ---------------------------------- Find By IDs -------------------------
+func (r *postgresStationRepositoryType) FindByIDs(ctx context.Context, stationIDs []int) ([]StationModel, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+postgresStationColumns+` FROM stations WHERE id = ANY($1)`, stationIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+
+	return collectPostgresStations(rows)
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *postgresStationRepositoryType) Insert(ctx context.Context, station *StationModel) error {
+	now := time.Now()
+	objectID := primitive.NewObjectID()
+
+	args := append(postgresStationArgs(*station, now), objectID.Hex())
+	tag, err := r.pool.Exec(ctx, postgresInsertStation+` ON CONFLICT (id) DO NOTHING`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert station: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrStationExists
+	}
+
+	station.ID = objectID
+	station.CreatedAt = primitive.NewDateTimeFromTime(now)
+	station.UpdatedAt = station.CreatedAt
+	return nil
+}
+
+// ---------------------------------- Update -------------------------
+func (r *postgresStationRepositoryType) Update(ctx context.Context, station *StationModel) error {
+	now := time.Now()
+
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE stations SET `+postgresSetColumns+` WHERE id = $1`, postgresStationArgs(*station, now)...)
+	if err != nil {
+		return fmt.Errorf("failed to update station: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrStationNotFound
+	}
+
+	station.UpdatedAt = primitive.NewDateTimeFromTime(now)
+	return nil
+}
+
+// ---------------------------------- Delete -------------------------
+func (r *postgresStationRepositoryType) Delete(ctx context.Context, stationID int) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM stations WHERE id = $1`, stationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete station: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrStationNotFound
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find Search Candidates -------------------------
+func (r *postgresStationRepositoryType) FindSearchCandidates(ctx context.Context) ([]StationModel, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+postgresStationColumns+` FROM stations WHERE active = 1 AND deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+
+	return collectPostgresStations(rows)
+}
+
+// ---------------------------------- Find All -------------------------
+func (r *postgresStationRepositoryType) FindAll(ctx context.Context) ([]StationModel, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+postgresStationColumns+` FROM stations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+
+	return collectPostgresStations(rows)
+}
+
+// ---------------------------------- Find All IDs -------------------------
+func (r *postgresStationRepositoryType) FindAllIDs(ctx context.Context) ([]int, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id FROM stations WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station ids: %w", err)
+	}
+
+	stationIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stationIDs, nil
+}
+
+// ---------------------------------- Deactivate Many -------------------------
+func (r *postgresStationRepositoryType) DeactivateMany(ctx context.Context, stationIDs []int, comment string) error {
+	// Keep the original comment the same way invalid coordinates do, skipping
+	// stations that are already inactive
+	_, err := r.pool.Exec(ctx, `UPDATE stations SET
+		active = 0,
+		updated_at = $3,
+		comment = CASE WHEN comment IN ('', 'NULL') THEN $2
+			ELSE 'New Comment: ' || $2 || ' | Original Comment: ' || comment END
+	WHERE id = ANY($1) AND active <> 0`, stationIDs, comment, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to deactivate stations: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Soft Delete Many -------------------------
+func (r *postgresStationRepositoryType) SoftDeleteMany(ctx context.Context, stationIDs []int) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE stations SET deleted_at = $2, updated_at = $2 WHERE id = ANY($1)`, stationIDs, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to soft delete stations: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find Nearest Station -------------------------
+func (r *postgresStationRepositoryType) FindNearestStation(ctx context.Context, data NearestStationRequest) ([]NearestStationData, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, name, en_name, lat, long, ST_Distance(location, `+postgresPoint+`)
+	FROM stations
+	WHERE `+postgresGeoFilter+` AND ST_DWithin(location, `+postgresPoint+`, $3)
+	ORDER BY location <-> `+postgresPoint+`, id
+	LIMIT $4`, data.Long, data.Lat, float64(nearestMaxDistanceKm*1000), data.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute nearest query: %w", err)
+	}
+
+	responses, err := collectNearestStations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(responses) == 0 {
+		return nil, ErrNoStationsNearby
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Nearest Station Pagination -------------------------
+func (r *postgresStationRepositoryType) FindNearestStationPagination(ctx context.Context, data NearestStationPaginationRequest) ([]NearestStationData, int, error) {
+	var totalItems int
+	if err := r.pool.QueryRow(ctx, `SELECT count(*) FROM stations WHERE `+postgresGeoFilter).Scan(&totalItems); err != nil {
+		return nil, 0, fmt.Errorf("failed to count stations: %w", err)
+	}
+
+	if totalItems == 0 {
+		return nil, 0, fmt.Errorf("no results returned")
+	}
+
+	start := (data.Page - 1) * data.PageSize
+
+	rows, err := r.pool.Query(ctx, `SELECT id, name, en_name, lat, long, ST_Distance(location, `+postgresPoint+`)
+	FROM stations
+	WHERE `+postgresGeoFilter+`
+	ORDER BY location <-> `+postgresPoint+`, id
+	LIMIT $3 OFFSET $4`, data.Long, data.Lat, data.PageSize, start)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute nearest query: %w", err)
+	}
+
+	responses, err := collectNearestStations(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return responses, totalItems, nil
+}
+
+func collectNearestStations(rows pgx.Rows) ([]NearestStationData, error) {
+	defer rows.Close()
+
+	responses := make([]NearestStationData, 0)
+	for rows.Next() {
+		var station NearestStationData
+		var distance float64
+
+		if err := rows.Scan(&station.ID, &station.Name, &station.EnName, &station.Lat, &station.Long, &distance); err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		station.Distance = math.Round((distance/1000)*1000) / 1000
+		responses = append(responses, station)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Within -------------------------
+func (r *postgresStationRepositoryType) FindWithin(ctx context.Context, data StationWithinRequest) ([]StationWithinData, error) {
+	shape, err := json.Marshal(data.shape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode shape: %w", err)
+	}
+
+	// The geography cast gives geodesic edges, as $geoWithin uses
+	rows, err := r.pool.Query(ctx, `SELECT id, name, en_name, lat, long
+	FROM stations
+	WHERE `+postgresGeoFilter+` AND ST_Covers(ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)::geography, location)
+	ORDER BY id
+	LIMIT $2`, string(shape), data.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute within query: %w", err)
+	}
+	defer rows.Close()
+
+	responses := make([]StationWithinData, 0)
+	for rows.Next() {
+		var station StationWithinData
+		if err := rows.Scan(&station.ID, &station.Name, &station.EnName, &station.Lat, &station.Long); err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+		responses = append(responses, station)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Map Points -------------------------
+func (r *postgresStationRepositoryType) FindMapPoints(ctx context.Context) ([]StationMapPoint, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, name, en_name, lat, long, class, active, dual_track,
+		ST_X(location::geometry), ST_Y(location::geometry)
+	FROM stations
+	WHERE `+postgresMapFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer rows.Close()
+
+	var points []StationMapPoint
+	for rows.Next() {
+		var point StationMapPoint
+		var x, y float64
+
+		if err := rows.Scan(&point.StationID, &point.Name, &point.EnName, &point.Lat, &point.Long,
+			&point.Class, &point.Active, &point.DualTrack, &x, &y); err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		point.Location = &GeoJSONPointModel{Type: "Point", Coordinates: []float64{x, y}}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return points, nil
+}
+
+// ---------------------------------- Each Export Station -------------------------
+func (r *postgresStationRepositoryType) EachExportStation(ctx context.Context, data StationExportRequest, fn func(StationModel) error) error {
+	query := `SELECT ` + postgresStationColumns + ` FROM stations WHERE ` + postgresGeoFilter
+	var args []interface{}
+
+	if data.Lat != nil && data.Long != nil {
+		query += ` AND ST_DWithin(location, ` + postgresPoint + `, $3)`
+		args = append(args, *data.Long, *data.Lat, data.RadiusKm*1000)
+	}
+
+	rows, err := r.pool.Query(ctx, query+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		station, err := scanPostgresStation(rows)
+		if err != nil {
+			return fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		if err := fn(*station); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,256 @@
+package station
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresDistanceTolerance covers PostGIS measuring on the WGS84 spheroid
+// where Mongo and the reference use a sphere.
+const postgresDistanceTolerance = 0.005
+
+// openPostgresTestRepository migrates a scratch schema on POSTGRES_URL, which
+// needs PostGIS available, and drops it when the test ends.
+func openPostgresTestRepository(t *testing.T) (*pgxpool.Pool, StationRepository) {
+	t.Helper()
+
+	url := os.Getenv("POSTGRES_URL")
+	if url == "" {
+		t.Skip("POSTGRES_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("go_spinsoft_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse POSTGRES_URL: %v", err)
+	}
+	// public stays on the path for the PostGIS types
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := MigratePostgres(ctx, pool); err != nil {
+		t.Fatalf("MigratePostgres: %v", err)
+	}
+
+	return pool, NewPostgresStationRepository(pool)
+}
+
+func TestPostgresMigrate(t *testing.T) {
+	pool, _ := openPostgresTestRepository(t)
+	ctx := context.Background()
+
+	// A second run finds everything applied
+	if err := MigratePostgres(ctx, pool); err != nil {
+		t.Fatalf("MigratePostgres again: %v", err)
+	}
+
+	migrations, err := loadSQLMigrations("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var applied int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(migrations))
+	}
+
+	var gist bool
+	err = pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_indexes
+		WHERE schemaname = current_schema() AND indexname = 'stations_location_gist')`).Scan(&gist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gist {
+		t.Error("stations_location_gist index is missing")
+	}
+}
+
+func TestPostgresUpsertMany(t *testing.T) {
+	_, repo := openPostgresTestRepository(t)
+	ctx := context.Background()
+	stations := loadFixtureStations(t)
+
+	result, err := repo.UpsertMany(ctx, stations)
+	if err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+	if result.Inserted != len(stations) || result.Updated != 0 {
+		t.Errorf("first upsert: %d inserted, %d updated, want %d inserted", result.Inserted, result.Updated, len(stations))
+	}
+
+	stations[0].EnName = "Krung Thep Aphiwat"
+	result, err = repo.UpsertMany(ctx, stations[:3])
+	if err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+	if result.Inserted != 0 || result.Updated != 3 {
+		t.Errorf("second upsert: %d inserted, %d updated, want 3 updated", result.Inserted, result.Updated)
+	}
+
+	station, err := repo.FindByID(ctx, stations[0].StationID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if station.EnName != "Krung Thep Aphiwat" {
+		t.Errorf("en_name = %q after update", station.EnName)
+	}
+	if station.Location == nil || station.Location.Coordinates[0] != stations[0].Long || station.Location.Coordinates[1] != stations[0].Lat {
+		t.Errorf("location = %+v, want %v,%v", station.Location, stations[0].Long, stations[0].Lat)
+	}
+
+	invalid, err := repo.FindByID(ctx, 1016)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if invalid.Location != nil || invalid.Active != 0 {
+		t.Errorf("zero coordinate station stored with location %+v, active %d", invalid.Location, invalid.Active)
+	}
+}
+
+func TestPostgresFindNearestStation(t *testing.T) {
+	_, repo := openPostgresTestRepository(t)
+	ctx := context.Background()
+	stations := loadFixtureStations(t)
+
+	if _, err := repo.UpsertMany(ctx, stations); err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+
+	for _, query := range nearestQueries {
+		for _, limit := range []int{1, 3, 100} {
+			label := fmt.Sprintf("%s limit %d", query.name, limit)
+			want := referenceNearest(stations, query.lat, query.long, nearestMaxDistanceKm)
+			if len(want) > limit {
+				want = want[:limit]
+			}
+
+			got, err := repo.FindNearestStation(ctx, NearestStationRequest{Lat: query.lat, Long: query.long, Limit: limit})
+			if len(want) == 0 {
+				if !errors.Is(err, ErrNoStationsNearby) {
+					t.Errorf("%s: got %v, %v, want ErrNoStationsNearby", label, got, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", label, err)
+			}
+
+			assertSameNearest(t, label, got, want, postgresDistanceTolerance)
+		}
+	}
+}
+
+func TestPostgresFindNearestStationPagination(t *testing.T) {
+	_, repo := openPostgresTestRepository(t)
+	ctx := context.Background()
+	stations := loadFixtureStations(t)
+
+	if _, err := repo.UpsertMany(ctx, stations); err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+
+	const pageSize = 4
+	for _, query := range nearestQueries {
+		want := referenceNearest(stations, query.lat, query.long, 0)
+
+		var got []NearestStationData
+		for page := 1; page <= len(want)/pageSize+1; page++ {
+			data, total, err := repo.FindNearestStationPagination(ctx, NearestStationPaginationRequest{
+				Lat: query.lat, Long: query.long, Page: page, PageSize: pageSize,
+			})
+			if err != nil {
+				t.Fatalf("%s page %d: %v", query.name, page, err)
+			}
+			if total != len(want) {
+				t.Errorf("%s page %d: total %d, want %d", query.name, page, total, len(want))
+			}
+			got = append(got, data...)
+		}
+
+		assertSameNearest(t, query.name, got, want, postgresDistanceTolerance)
+	}
+}
+
+func TestPostgresFindWithin(t *testing.T) {
+	_, repo := openPostgresTestRepository(t)
+	ctx := context.Background()
+
+	if _, err := repo.UpsertMany(ctx, loadFixtureStations(t)); err != nil {
+		t.Fatalf("UpsertMany: %v", err)
+	}
+
+	memoryRepo, err := NewMemoryStationRepository(fixtureStationsFile)
+	if err != nil {
+		t.Fatalf("NewMemoryStationRepository: %v", err)
+	}
+
+	shapes := map[string]*geoWithinShape{
+		"central bangkok": bboxShape([4]float64{100.52, 13.74, 100.55, 13.79}),
+		"north":           bboxShape([4]float64{98.5, 18.0, 99.5, 19.0}),
+		"empty":           bboxShape([4]float64{101.0, 12.0, 101.5, 12.5}),
+		"polygon with hole": {
+			Type: GeometryPolygon,
+			Coordinates: [][][]float64{
+				{{100.50, 13.70}, {100.70, 13.70}, {100.70, 13.95}, {100.50, 13.95}, {100.50, 13.70}},
+				{{100.52, 13.74}, {100.55, 13.74}, {100.55, 13.79}, {100.52, 13.79}, {100.52, 13.74}},
+			},
+		},
+	}
+
+	for name, shape := range shapes {
+		for _, limit := range []int{2, 100} {
+			req := StationWithinRequest{shape: shape, Limit: limit}
+
+			want, err := memoryRepo.FindWithin(ctx, req)
+			if err != nil {
+				t.Fatalf("%s memory: %v", name, err)
+			}
+			got, err := repo.FindWithin(ctx, req)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if fmt.Sprint(stationWithinIDs(got)) != fmt.Sprint(stationWithinIDs(want)) {
+				t.Errorf("%s limit %d: got %v, want %v", name, limit, stationWithinIDs(got), stationWithinIDs(want))
+			}
+		}
+	}
+}
+
+func stationWithinIDs(stations []StationWithinData) []int {
+	ids := make([]int, len(stations))
+	for i, station := range stations {
+		ids[i] = station.ID
+	}
+	return ids
+}
@@ -0,0 +1,78 @@
+package station
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ImportReportRepository interface {
+	InsertMany(ctx context.Context, issues []StationImportIssue) error
+	EachByJob(ctx context.Context, jobID primitive.ObjectID, fn func(StationImportIssue) error) error
+	DeleteByJob(ctx context.Context, jobID primitive.ObjectID) error
+}
+
+type importReportRepositoryType struct {
+	collection *mongo.Collection
+}
+
+func NewImportReportRepository(collection *mongo.Collection) ImportReportRepository {
+	return &importReportRepositoryType{
+		collection: collection,
+	}
+}
+
+// ---------------------------------- Insert Many -------------------------
+func (r *importReportRepositoryType) InsertMany(ctx context.Context, issues []StationImportIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(issues))
+	for i, issue := range issues {
+		documents[i] = issue
+	}
+
+	if _, err := r.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to insert import report: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Each By Job -------------------------
+func (r *importReportRepositoryType) EachByJob(ctx context.Context, jobID primitive.ObjectID, fn func(StationImportIssue) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "index", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"job_id": jobID}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find import report: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var issue StationImportIssue
+		if err := cursor.Decode(&issue); err != nil {
+			return fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		if err := fn(issue); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// ---------------------------------- Delete By Job -------------------------
+func (r *importReportRepositoryType) DeleteByJob(ctx context.Context, jobID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"job_id": jobID}); err != nil {
+		return fmt.Errorf("failed to delete import report: %w", err)
+	}
+
+	return nil
+}
@@ -2,21 +2,42 @@ package station
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math"
 	"time"
 
+	"github.com/zombox0633/go_spinsoft/src/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var (
+	ErrStationNotFound  = errors.New("station not found")
+	ErrStationExists    = errors.New("station already exists")
+	ErrNoStationsNearby = errors.New("no stations found within 10km")
+)
+
 type StationRepository interface {
-	UpsertMany(ctx context.Context, stations []StationModel) error
+	UpsertMany(ctx context.Context, stations []StationModel) (*StationUpsertResult, error)
+	FindByID(ctx context.Context, stationID int) (*StationModel, error)
+	FindByIDs(ctx context.Context, stationIDs []int) ([]StationModel, error)
+	Insert(ctx context.Context, station *StationModel) error
+	Update(ctx context.Context, station *StationModel) error
+	Delete(ctx context.Context, stationID int) error
+	FindSearchCandidates(ctx context.Context) ([]StationModel, error)
+	FindAll(ctx context.Context) ([]StationModel, error)
+	FindAllIDs(ctx context.Context) ([]int, error)
+	DeactivateMany(ctx context.Context, stationIDs []int, comment string) error
+	SoftDeleteMany(ctx context.Context, stationIDs []int) error
 	FindNearestStation(ctx context.Context, data NearestStationRequest) ([]NearestStationData, error)
 	FindNearestStationPagination(ctx context.Context, data NearestStationPaginationRequest) ([]NearestStationData, int, error)
-	CreateGeoIndex(ctx context.Context) error
+	FindWithin(ctx context.Context, data StationWithinRequest) ([]StationWithinData, error)
+	FindMapPoints(ctx context.Context) ([]StationMapPoint, error)
+	EachExportStation(ctx context.Context, data StationExportRequest, fn func(StationModel) error) error
 }
 
 type stationRepositoryType struct {
@@ -30,9 +51,9 @@ func NewStationRepository(collection *mongo.Collection) StationRepository {
 }
 
 // ---------------------------------- ImportFromURL -------------------------
-func (r *stationRepositoryType) UpsertMany(ctx context.Context, stations []StationModel) error {
+func (r *stationRepositoryType) UpsertMany(ctx context.Context, stations []StationModel) (*StationUpsertResult, error) {
 	if len(stations) == 0 {
-		return nil
+		return &StationUpsertResult{}, nil
 	}
 
 	now := primitive.NewDateTimeFromTime(time.Now())
@@ -44,31 +65,14 @@ func (r *stationRepositoryType) UpsertMany(ctx context.Context, stations []Stati
 		filter := bson.M{"id": station.StationID}
 
 		update := bson.M{
-			"$set": bson.M{
-				"station_code":    station.StationCode,
-				"name":            station.Name,
-				"en_name":         station.EnName,
-				"th_short":        station.ThShort,
-				"en_short":        station.EnShort,
-				"chname":          station.ChName,
-				"controldivision": station.ControlDiv,
-				"exact_km":        station.ExactKM,
-				"exact_distance":  station.ExactDistance,
-				"km":              station.KM,
-				"class":           station.Class,
-				"lat":             station.Lat,
-				"long":            station.Long,
-				"location":        station.Location,
-				"active":          station.Active,
-				"giveway":         station.Giveway,
-				"dual_track":      station.DualTrack,
-				"comment":         station.Comment,
-				"updated_at":      now,
-			},
+			"$set": stationSetFields(station, now),
 			"$setOnInsert": bson.M{
 				"id":         station.StationID,
 				"created_at": now,
 			},
+			"$unset": bson.M{
+				"deleted_at": "",
+			},
 		}
 
 		operation := mongo.NewUpdateManyModel()
@@ -81,11 +85,230 @@ func (r *stationRepositoryType) UpsertMany(ctx context.Context, stations []Stati
 
 	result, err := r.collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
 	if err != nil {
-		return fmt.Errorf("failed to insert stations: %w", err)
+		return nil, fmt.Errorf("failed to insert stations: %w", err)
 	}
 
-	fmt.Printf("Successfully processed %d stations: %d inserted, %d updated\n",
+	log.Printf("Successfully processed %d stations: %d inserted, %d updated",
 		len(stations), result.UpsertedCount, result.ModifiedCount)
+	return &StationUpsertResult{
+		Inserted: int(result.UpsertedCount),
+		Updated:  int(result.ModifiedCount),
+	}, nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *stationRepositoryType) FindByID(ctx context.Context, stationID int) (*StationModel, error) {
+	var station StationModel
+
+	err := r.collection.FindOne(ctx, bson.M{"id": stationID}).Decode(&station)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station: %w", err)
+	}
+
+	return &station, nil
+}
+
+// ---------------------------------- Find By IDs -------------------------
+func (r *stationRepositoryType) FindByIDs(ctx context.Context, stationIDs []int) ([]StationModel, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"id": bson.M{"$in": stationIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stations []StationModel
+	if err := cursor.All(ctx, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *stationRepositoryType) Insert(ctx context.Context, station *StationModel) error {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"id": station.StationID})
+	if err != nil {
+		return fmt.Errorf("failed to check station: %w", err)
+	}
+	if count > 0 {
+		return ErrStationExists
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	station.ID = primitive.NilObjectID
+	station.CreatedAt = now
+	station.UpdatedAt = now
+
+	// The count is only a fast path; the id_unique index from migration 0006
+	// catches concurrent inserts of the same id
+	result, err := r.collection.InsertOne(ctx, station)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrStationExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert station: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		station.ID = id
+	}
+
+	return nil
+}
+
+// ---------------------------------- Update -------------------------
+func (r *stationRepositoryType) Update(ctx context.Context, station *StationModel) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"id": station.StationID},
+		bson.M{"$set": stationSetFields(*station, now)},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update station: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrStationNotFound
+	}
+
+	station.UpdatedAt = now
+	return nil
+}
+
+// ---------------------------------- Delete -------------------------
+func (r *stationRepositoryType) Delete(ctx context.Context, stationID int) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"id": stationID})
+	if err != nil {
+		return fmt.Errorf("failed to delete station: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrStationNotFound
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find Search Candidates -------------------------
+func (r *stationRepositoryType) FindSearchCandidates(ctx context.Context) ([]StationModel, error) {
+	projection := bson.M{
+		"id":       1,
+		"name":     1,
+		"en_name":  1,
+		"th_short": 1,
+		"en_short": 1,
+		"chname":   1,
+		"lat":      1,
+		"long":     1,
+	}
+
+	filter := bson.M{
+		"active":     1,
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stations []StationModel
+	if err := cursor.All(ctx, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Find All -------------------------
+func (r *stationRepositoryType) FindAll(ctx context.Context) ([]StationModel, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stations []StationModel
+	if err := cursor.All(ctx, &stations); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Find All IDs -------------------------
+func (r *stationRepositoryType) FindAllIDs(ctx context.Context) ([]int, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": false}}
+	opts := options.Find().SetProjection(bson.M{"_id": 0, "id": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station ids: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		StationID int `bson:"id"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	stationIDs := make([]int, len(results))
+	for i, result := range results {
+		stationIDs[i] = result.StationID
+	}
+
+	return stationIDs, nil
+}
+
+// ---------------------------------- Deactivate Many -------------------------
+func (r *stationRepositoryType) DeactivateMany(ctx context.Context, stationIDs []int, comment string) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	// Keep the original comment the same way invalid coordinates do. Stations
+	// that are already inactive are skipped, so the comment is not wrapped again.
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"active":     0,
+			"updated_at": now,
+			"comment": bson.M{"$cond": bson.A{
+				bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$comment", ""}}, bson.A{"", "NULL"}}},
+				comment,
+				bson.M{"$concat": bson.A{"New Comment: ", comment, " | Original Comment: ", "$comment"}},
+			}},
+		}}},
+	}
+
+	filter := bson.M{"id": bson.M{"$in": stationIDs}, "active": bson.M{"$ne": 0}}
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to deactivate stations: %w", err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Soft Delete Many -------------------------
+func (r *stationRepositoryType) SoftDeleteMany(ctx context.Context, stationIDs []int) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	update := bson.M{
+		"$set": bson.M{
+			"deleted_at": now,
+			"updated_at": now,
+		},
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"id": bson.M{"$in": stationIDs}}, update); err != nil {
+		return fmt.Errorf("failed to soft delete stations: %w", err)
+	}
+
 	return nil
 }
 
@@ -103,8 +326,9 @@ func (r *stationRepositoryType) FindNearestStation(ctx context.Context, data Nea
 			"maxDistance":   10000, //10km
 			"spherical":     true,
 			"query": bson.M{
-				"active":   1,
-				"location": bson.M{"$exists": true},
+				"active":     1,
+				"location":   bson.M{"$exists": true},
+				"deleted_at": bson.M{"$exists": false},
 			},
 		}}},
 		{{Key: "$limit", Value: data.Limit}},
@@ -130,7 +354,7 @@ func (r *stationRepositoryType) FindNearestStation(ctx context.Context, data Nea
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no stations found within 100km")
+		return nil, ErrNoStationsNearby
 	}
 
 	responses := make([]NearestStationData, len(results))
@@ -168,8 +392,9 @@ func (r *stationRepositoryType) FindNearestStationPagination(ctx context.Context
 			"distanceField": "distance",
 			"spherical":     true,
 			"query": bson.M{
-				"active":   1,
-				"location": bson.M{"$exists": true},
+				"active":     1,
+				"location":   bson.M{"$exists": true},
+				"deleted_at": bson.M{"$exists": false},
 			},
 		}}},
 		{{Key: "$project", Value: bson.M{
@@ -243,18 +468,154 @@ func (r *stationRepositoryType) FindNearestStationPagination(ctx context.Context
 	return responses, totalItems, nil
 }
 
-// ---------------------------------- CreateGeoIndex -------------------------
-func (r *stationRepositoryType) CreateGeoIndex(ctx context.Context) error {
-	indexStation := mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "location", Value: "2dsphere"},
+// ---------------------------------- Find Within -------------------------
+
+// FindWithin uses the same filters and fields as the nearest queries. It reads
+// one station past the limit so the caller can tell the result was cut.
+func (r *stationRepositoryType) FindWithin(ctx context.Context, data StationWithinRequest) ([]StationWithinData, error) {
+	filter := bson.M{
+		"location": bson.M{
+			"$geoWithin": bson.M{"$geometry": data.shape},
 		},
-		Options: options.Index().SetName("location_2dsphere"),
+		"active":     1,
+		"deleted_at": bson.M{"$exists": false},
 	}
 
-	if _, err := r.collection.Indexes().CreateOne(ctx, indexStation); err != nil {
-		return fmt.Errorf("failed to create geo index: %w", err)
+	findOptions := options.Find().
+		SetProjection(bson.M{"_id": 0, "id": 1, "name": 1, "en_name": 1, "lat": 1, "long": 1}).
+		SetSort(bson.M{"id": 1}).
+		SetLimit(int64(data.Limit + 1))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute geoWithin: %w", err)
 	}
+	defer cursor.Close(ctx)
 
-	return nil
+	var results []struct {
+		StationID int     `bson:"id"`
+		Name      string  `bson:"name"`
+		EnName    string  `bson:"en_name"`
+		Lat       float64 `bson:"lat"`
+		Long      float64 `bson:"long"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	responses := make([]StationWithinData, len(results))
+	for i, station := range results {
+		responses[i] = StationWithinData{
+			ID:     station.StationID,
+			Name:   station.Name,
+			EnName: station.EnName,
+			Lat:    station.Lat,
+			Long:   station.Long,
+		}
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Map Points -------------------------
+// FindMapPoints includes inactive stations; tiles show them by their active
+// property.
+func (r *stationRepositoryType) FindMapPoints(ctx context.Context) ([]StationMapPoint, error) {
+	filter := bson.M{
+		"location":   bson.M{"$exists": true},
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	projection := bson.M{
+		"_id":        0,
+		"id":         1,
+		"name":       1,
+		"en_name":    1,
+		"lat":        1,
+		"long":       1,
+		"class":      1,
+		"active":     1,
+		"dual_track": 1,
+		"location":   1,
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var points []StationMapPoint
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return points, nil
+}
+
+// ---------------------------------- Each Export Station -------------------------
+
+// EachExportStation streams the stations the nearest queries would consider,
+// optionally limited to a radius around a point, in station id order.
+func (r *stationRepositoryType) EachExportStation(ctx context.Context, data StationExportRequest, fn func(StationModel) error) error {
+	filter := bson.M{
+		"active":     1,
+		"location":   bson.M{"$exists": true},
+		"deleted_at": bson.M{"$exists": false},
+	}
+
+	if data.Lat != nil && data.Long != nil {
+		filter["location"] = bson.M{
+			"$geoWithin": bson.M{
+				"$centerSphere": bson.A{
+					bson.A{*data.Long, *data.Lat},
+					data.RadiusKm / utils.EarthRadiusKm,
+				},
+			},
+		}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var station StationModel
+		if err := cursor.Decode(&station); err != nil {
+			return fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		if err := fn(station); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func stationSetFields(station StationModel, now primitive.DateTime) bson.M {
+	return bson.M{
+		"station_code":    station.StationCode,
+		"name":            station.Name,
+		"en_name":         station.EnName,
+		"th_short":        station.ThShort,
+		"en_short":        station.EnShort,
+		"chname":          station.ChName,
+		"controldivision": station.ControlDiv,
+		"exact_km":        station.ExactKM,
+		"exact_distance":  station.ExactDistance,
+		"km":              station.KM,
+		"class":           station.Class,
+		"lat":             station.Lat,
+		"long":            station.Long,
+		"location":        station.Location,
+		"active":          station.Active,
+		"giveway":         station.Giveway,
+		"dual_track":      station.DualTrack,
+		"comment":         station.Comment,
+		"updated_at":      now,
+	}
 }
@@ -3,30 +3,95 @@ package station
 import (
 	"context"
 	"log"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/zombox0633/go_spinsoft/src/georules"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func StationRoutes(api fiber.Router, DB *mongo.Database) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+type StationOptions struct {
+	ImportWorkers int
+	GeoRulesFile  string
+	// Repository replaces the stations collection when set
+	Repository StationRepository
+}
 
-	collection := DB.Collection("stations")
-	stationRepo := NewStationRepository(collection)
+// OpenStationService wires the station service to its repositories. Without
+// MongoDB there are no import jobs or history, only opts.Repository, and with
+// neither only parsing and validation work.
+func OpenStationService(DB *mongo.Database, opts StationOptions) StationService {
+	stationRepo := opts.Repository
+	if stationRepo == nil && DB != nil {
+		stationRepo = NewStationRepository(DB.Collection("stations"))
+	}
 
-	if err := stationRepo.CreateGeoIndex(ctx); err != nil {
-		log.Printf("Warning: Failed to create geo index: %v", err)
+	geoRules, err := georules.LoadEngine(opts.GeoRulesFile)
+	if err != nil {
+		log.Printf("Warning: Geo rules disabled: %v", err)
+	} else {
+		log.Printf("Loaded %d geo rules from %s", geoRules.Len(), opts.GeoRulesFile)
 	}
-	log.Print("Station index ready")
 
-	stationService := NewStationService(stationRepo)
+	if DB == nil {
+		return NewStationService(stationRepo, nil, nil, nil, geoRules)
+	}
+
+	importJobRepo := NewImportJobRepository(DB.Collection("import_jobs"))
+	historyRepo := NewStationHistoryRepository(DB.Collection("station_history"), DB.Collection("station_history_counters"))
+	importReportRepo := NewImportReportRepository(DB.Collection("import_job_issues"))
+
+	return NewStationService(stationRepo, importJobRepo, historyRepo, importReportRepo, geoRules)
+}
+
+// StationRoutes registers the station endpoints and runs the import workers
+// until ctx is cancelled.
+func StationRoutes(ctx context.Context, api fiber.Router, DB *mongo.Database, opts StationOptions) {
+	stationService := OpenStationService(DB, opts)
+	stationService.StartImportWorkers(ctx, opts.ImportWorkers)
+
 	stationController := NewStationController(stationService)
 
 	stationGroup := api.Group("/station")
 
 	stationGroup.Post("/import", stationController.PostImportStationsURL)
+	stationGroup.Post("/import/upload", stationController.PostImportStationsFile)
+	stationGroup.Get("/import/:id", stationController.GetImportJob)
+	stationGroup.Get("/import/:id/report.csv", stationController.GetImportReport)
 	stationGroup.Get("/nearest", stationController.GetNearestStation)
 	stationGroup.Get("/nearest-pagination", stationController.GetNearestStationPagination)
+	stationGroup.Get("/search", stationController.GetSearchStations)
+	stationGroup.Get("/within", stationController.GetStationsWithin)
+	stationGroup.Post("/within", stationController.PostStationsWithin)
+	stationGroup.Get("/clusters", stationController.GetStationClusters)
+	stationGroup.Get("/export", stationController.GetStationExport)
+
+	stationGroup.Post("/", stationController.PostStation)
+	stationGroup.Get("/:id<int>", stationController.GetStation)
+	stationGroup.Get("/:id<int>/history", stationController.GetStationHistory)
+	stationGroup.Put("/:id<int>", stationController.PutStation)
+	stationGroup.Patch("/:id<int>", stationController.PatchStation)
+	stationGroup.Delete("/:id<int>", stationController.DeleteStation)
+
+	api.Get("/tiles/stations/:z<int>/:x<int>/:y<int>.pbf", stationController.GetStationTile)
+}
+
+// OfflineStationRoutes serves the read-only station endpoints from a local
+// store, for deployments without MongoDB. Imports, edits and history need
+// the full StationRoutes.
+func OfflineStationRoutes(api fiber.Router, stationRepo StationRepository) {
+	stationService := NewStationService(stationRepo, nil, nil, nil, nil)
+	stationController := NewStationController(stationService)
+
+	stationGroup := api.Group("/station")
+
+	stationGroup.Get("/nearest", stationController.GetNearestStation)
+	stationGroup.Get("/nearest-pagination", stationController.GetNearestStationPagination)
+	stationGroup.Get("/search", stationController.GetSearchStations)
+	stationGroup.Get("/within", stationController.GetStationsWithin)
+	stationGroup.Post("/within", stationController.PostStationsWithin)
+	stationGroup.Get("/clusters", stationController.GetStationClusters)
+	stationGroup.Get("/export", stationController.GetStationExport)
+	stationGroup.Get("/:id<int>", stationController.GetStation)
+
+	api.Get("/tiles/stations/:z<int>/:x<int>/:y<int>.pbf", stationController.GetStationTile)
 }
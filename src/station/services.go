@@ -2,79 +2,381 @@ package station
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/zombox0633/go_spinsoft/src/georules"
 	"github.com/zombox0633/go_spinsoft/src/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var ErrInvalidStation = errors.New("invalid station")
+
 type StationService interface {
-	ImportFromURL(ctx context.Context, url string) (*StationImportResponse, error)
+	ImportFromURL(ctx context.Context, req StationImportRequest) (*StationImportResponse, error)
+	DiffFromURL(ctx context.Context, url string) (*StationImportDiffResponse, error)
+	ParseStationFile(filename string, r io.Reader, opts StationFileImportRequest) ([]StationModel, error)
+	ImportStations(ctx context.Context, stations []StationModel, opts StationImportOptions) (*StationImportResponse, error)
+	DiffStations(ctx context.Context, stations []StationModel) (*StationImportDiffResponse, error)
+	CreateImportJob(ctx context.Context, req StationImportRequest) (*ImportJobResponse, error)
+	GetImportJob(ctx context.Context, id primitive.ObjectID) (*ImportJobResponse, error)
+	WriteImportReportCSV(ctx context.Context, id primitive.ObjectID, w io.Writer) error
+	StartImportWorkers(ctx context.Context, workers int)
+	GetStation(ctx context.Context, stationID int) (*StationResponse, error)
+	GetStationAsOf(ctx context.Context, stationID int, asOf time.Time) (*StationResponse, error)
+	GetStationHistory(ctx context.Context, stationID int, limit int) (*StationHistoryResponse, error)
+	CreateStation(ctx context.Context, station StationModel) (*StationResponse, error)
+	ReplaceStation(ctx context.Context, stationID int, station StationModel) (*StationResponse, error)
+	PatchStation(ctx context.Context, stationID int, patch StationPatchRequest) (*StationResponse, error)
+	DeleteStation(ctx context.Context, stationID int) (*StationDeleteResponse, error)
+	SearchStations(ctx context.Context, data StationSearchRequest) (*StationSearchResponse, error)
 	FindNearestStation(ctx context.Context, data NearestStationRequest) (*NearestStationResponse, error)
 	FindNearestStationPagination(ctx context.Context, data NearestStationPaginationRequest) (*NearestStationPaginationResponse, error)
+	FindStationsInBBox(ctx context.Context, bbox [4]float64, limit int) (*StationWithinResponse, error)
+	FindStationsWithin(ctx context.Context, geometry GeoJSONGeometry, limit int) (*StationWithinResponse, error)
+	FindStationClusters(ctx context.Context, bbox [4]float64, zoom int) (*StationClusterResponse, error)
+	StationTile(ctx context.Context, z, x, y int) ([]byte, error)
+	ExportStations(ctx context.Context, req StationExportRequest, w io.Writer) error
+	ValidateStations(stations []StationModel) *StationValidationResponse
+	ReindexStations(ctx context.Context) (*StationReindexResponse, error)
 }
 
 type stationServiceType struct {
-	repo       StationRepository
-	httpClient *http.Client
+	repo        StationRepository
+	jobRepo     ImportJobRepository
+	historyRepo StationHistoryRepository
+	reportRepo  ImportReportRepository
+	geoRules    *georules.Engine
+	mapCache    *stationMapCache
+	jobSignal   chan struct{}
+	workerID    string
+	httpClient  *http.Client
 }
 
-func NewStationService(repo StationRepository) StationService {
+func NewStationService(repo StationRepository, jobRepo ImportJobRepository, historyRepo StationHistoryRepository, reportRepo ImportReportRepository, geoRules *georules.Engine) StationService {
 	return &stationServiceType{
-		repo: repo,
+		repo:        repo,
+		jobRepo:     jobRepo,
+		historyRepo: historyRepo,
+		reportRepo:  reportRepo,
+		geoRules:    geoRules,
+		mapCache:    newStationMapCache(),
+		jobSignal:   make(chan struct{}, 1),
+		workerID:    newImportWorkerID(),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: 10 * time.Minute,
 		},
 	}
 }
 
 // ---------------------------------- ImportFromURL -------------------------
-func (s *stationServiceType) ImportFromURL(ctx context.Context, url string) (*StationImportResponse, error) {
-	resp, err := s.httpClient.Get(url)
+func (s *stationServiceType) ImportFromURL(ctx context.Context, req StationImportRequest) (*StationImportResponse, error) {
+	return s.importFromURL(ctx, req, nil)
+}
+
+func (s *stationServiceType) importFromURL(ctx context.Context, req StationImportRequest, onProgress func(StationImportResponse)) (*StationImportResponse, error) {
+	opts, err := req.StationImportOptions.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.openURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	writer := s.newStationBatchWriter(ctx, opts, onProgress)
+	streamErr := (&jsonStationParser{}).Stream(body, writer.add)
+
+	return writer.finish(streamErr), nil
+}
+
+// ---------------------------------- Import Stations -------------------------
+func (s *stationServiceType) ImportStations(ctx context.Context, stations []StationModel, opts StationImportOptions) (*StationImportResponse, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	writer := s.newStationBatchWriter(ctx, opts, nil)
+	for _, station := range stations {
+		if err := writer.add(station); err != nil {
+			return writer.finish(err), nil
+		}
+	}
+
+	return writer.finish(nil), nil
+}
+
+func (s *stationServiceType) fetchStations(ctx context.Context, url string) ([]StationModel, error) {
+	body, err := s.openURL(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return (&jsonStationParser{}).Parse(body)
+}
+
+func (s *stationServiceType) openURL(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch data: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	return resp.Body, nil
+}
+
+// ---------------------------------- Parse Station File -------------------------
+func (s *stationServiceType) ParseStationFile(filename string, r io.Reader, opts StationFileImportRequest) ([]StationModel, error) {
+	parser, err := NewStationParser(opts.Format, filename, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data: %w", err)
+		return nil, err
 	}
 
-	var stations []StationModel
-	if err := json.Unmarshal(body, &stations); err != nil {
-		return nil, fmt.Errorf("failed to parse: %w", err)
+	stations, err := parser.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStation, err.Error())
 	}
 
-	invalidCoordinateCount := 0
-	for _, station := range stations {
-		if station.WasInvalidated {
-			invalidCoordinateCount++
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("%w: file contains no stations", ErrInvalidStation)
+	}
+
+	return stations, nil
+}
+
+// ---------------------------------- Get Station -------------------------
+func (s *stationServiceType) GetStation(ctx context.Context, stationID int) (*StationResponse, error) {
+	station, err := s.repo.FindByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StationResponse{
+		Success: true,
+		Data:    station,
+	}, nil
+}
+
+// ---------------------------------- Create Station -------------------------
+func (s *stationServiceType) CreateStation(ctx context.Context, station StationModel) (*StationResponse, error) {
+	if station.StationID < 1 {
+		return nil, fmt.Errorf("%w: id must be greater than 0", ErrInvalidStation)
+	}
+
+	if err := s.validateStation(&station); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Insert(ctx, &station); err != nil {
+		return nil, err
+	}
+
+	s.mapCache.invalidate()
+	if err := s.recordStationChange(ctx, StationChangeCreate, nil, station); err != nil {
+		return nil, err
+	}
+
+	return &StationResponse{
+		Success: true,
+		Data:    &station,
+	}, nil
+}
+
+// ---------------------------------- Replace Station -------------------------
+func (s *stationServiceType) ReplaceStation(ctx context.Context, stationID int, station StationModel) (*StationResponse, error) {
+	if station.StationID != 0 && station.StationID != stationID {
+		return nil, fmt.Errorf("%w: id in body does not match id in path", ErrInvalidStation)
+	}
+	station.StationID = stationID
+
+	if err := s.validateStation(&station); err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.FindByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, &station); err != nil {
+		return nil, err
+	}
+
+	s.mapCache.invalidate()
+	if err := s.recordStationChange(ctx, StationChangeUpdate, before, station); err != nil {
+		return nil, err
+	}
+
+	return s.GetStation(ctx, stationID)
+}
+
+// ---------------------------------- Patch Station -------------------------
+func (s *stationServiceType) PatchStation(ctx context.Context, stationID int, patch StationPatchRequest) (*StationResponse, error) {
+	station, err := s.repo.FindByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *station
+	patch.apply(station)
+
+	if patch.Lat != nil || patch.Long != nil {
+		if reason := station.coordinateError(); reason != "" {
+			station.invalidate(reason)
+		} else {
+			station.syncLocation()
+		}
+
+		if err := s.validateStation(station); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, station); err != nil {
+		return nil, err
+	}
+
+	s.mapCache.invalidate()
+	if err := s.recordStationChange(ctx, StationChangeUpdate, &before, *station); err != nil {
+		return nil, err
+	}
+
+	return &StationResponse{
+		Success: true,
+		Data:    station,
+	}, nil
+}
+
+// validateStation applies the configured geographic rules, then rejects
+// coordinates a single station write cannot fix up. The rules run first so
+// swapped coordinates are reported as swapped rather than out of range.
+func (s *stationServiceType) validateStation(station *StationModel) error {
+	outOfBounds := station.WasInvalidated
+	station.applyGeoRules(s.geoRules)
+
+	if outOfBounds {
+		return fmt.Errorf("%w: %s", ErrInvalidStation, station.InvalidReason)
+	}
+
+	for _, issue := range station.Issues {
+		if issue.Action == IssueActionRejected {
+			return fmt.Errorf("%w: %s", ErrInvalidStation, issue.Reason)
 		}
 	}
 
-	if err := s.repo.UpsertMany(ctx, stations); err != nil {
-		return &StationImportResponse{
-			Success:            false,
-			ImportedCount:      0,
-			InvalidCoordinates: invalidCoordinateCount,
-			Message:            err.Error(),
-		}, nil
+	return nil
+}
+
+// ---------------------------------- Delete Station -------------------------
+func (s *stationServiceType) DeleteStation(ctx context.Context, stationID int) (*StationDeleteResponse, error) {
+	before, err := s.repo.FindByID(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Delete(ctx, stationID); err != nil {
+		return nil, err
+	}
+
+	s.mapCache.invalidate()
+	if err := s.recordStationChange(ctx, StationChangeDelete, before, *before); err != nil {
+		return nil, err
 	}
 
-	return &StationImportResponse{
-		Success:            true,
-		ImportedCount:      len(stations),
-		InvalidCoordinates: invalidCoordinateCount,
-		Message:            "Import completed successfully",
+	return &StationDeleteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Station %d deleted successfully", stationID),
+	}, nil
+}
+
+// ---------------------------------- Search Stations -------------------------
+func (s *stationServiceType) SearchStations(ctx context.Context, data StationSearchRequest) (*StationSearchResponse, error) {
+	query := utils.NormalizeSearchText(data.Query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidStation)
+	}
+
+	if data.Limit < 1 || data.Limit > 50 {
+		return nil, fmt.Errorf("%w: invalid limit: must be between 1 and 50", ErrInvalidStation)
+	}
+
+	hasPoint := data.Lat != nil && data.Long != nil
+	if hasPoint {
+		if err := utils.ValidateCoordinates(*data.Lat, *data.Long); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidStation, err.Error())
+		}
+	}
+
+	candidates, err := s.repo.FindSearchCandidates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stations: %w", err)
+	}
+
+	results := make([]StationSearchData, 0)
+	for _, station := range candidates {
+		field, score := matchStationName(station, query)
+		if score == 0 {
+			continue
+		}
+
+		result := StationSearchData{
+			ID:           station.StationID,
+			Name:         station.Name,
+			EnName:       station.EnName,
+			ThShort:      station.ThShort,
+			EnShort:      station.EnShort,
+			ChName:       station.ChName,
+			Lat:          station.Lat,
+			Long:         station.Long,
+			MatchedField: field,
+			Score:        score,
+		}
+
+		if hasPoint {
+			distanceKm := math.Round(utils.HaversineKm(*data.Lat, *data.Long, station.Lat, station.Long)*1000) / 1000
+			result.Distance = &distanceKm
+		}
+
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if hasPoint {
+			return *results[i].Distance < *results[j].Distance
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if len(results) > data.Limit {
+		results = results[:data.Limit]
+	}
+
+	return &StationSearchResponse{
+		Success: true,
+		Query:   data.Query,
+		Data:    results,
 	}, nil
 }
 
@@ -139,3 +441,157 @@ func (s *stationServiceType) FindNearestStationPagination(ctx context.Context, d
 	}
 	return response, nil
 }
+
+func (p StationPatchRequest) apply(station *StationModel) {
+	if p.StationCode != nil {
+		station.StationCode = *p.StationCode
+	}
+	if p.Name != nil {
+		station.Name = *p.Name
+	}
+	if p.EnName != nil {
+		station.EnName = *p.EnName
+	}
+	if p.ThShort != nil {
+		station.ThShort = *p.ThShort
+	}
+	if p.EnShort != nil {
+		station.EnShort = *p.EnShort
+	}
+	if p.ChName != nil {
+		station.ChName = *p.ChName
+	}
+	if p.ControlDiv != nil {
+		station.ControlDiv = *p.ControlDiv
+	}
+	if p.ExactKM != nil {
+		station.ExactKM = *p.ExactKM
+	}
+	if p.ExactDistance != nil {
+		station.ExactDistance = *p.ExactDistance
+	}
+	if p.KM != nil {
+		station.KM = *p.KM
+	}
+	if p.Class != nil {
+		station.Class = *p.Class
+	}
+	if p.Lat != nil {
+		station.Lat = *p.Lat
+	}
+	if p.Long != nil {
+		station.Long = *p.Long
+	}
+	if p.Active != nil {
+		station.Active = *p.Active
+	}
+	if p.Giveway != nil {
+		station.Giveway = *p.Giveway
+	}
+	if p.DualTrack != nil {
+		station.DualTrack = *p.DualTrack
+	}
+	if p.Comment != nil {
+		station.Comment = *p.Comment
+	}
+}
+
+const (
+	searchScoreExact       = 100
+	searchScorePrefix      = 80
+	searchScoreWordPrefix  = 60
+	searchScoreSubstring   = 40
+	searchShortNamePenalty = 5
+)
+
+func matchStationName(station StationModel, query string) (string, int) {
+	fields := []struct {
+		key     string
+		value   string
+		penalty int
+	}{
+		{"name", station.Name, 0},
+		{"en_name", station.EnName, 0},
+		{"chname", station.ChName, 0},
+		{"th_short", station.ThShort, searchShortNamePenalty},
+		{"en_short", station.EnShort, searchShortNamePenalty},
+	}
+
+	bestField, bestScore := "", 0
+	for _, field := range fields {
+		score := matchSearchText(field.value, query)
+		if score == 0 {
+			continue
+		}
+
+		score -= field.penalty
+		if score > bestScore {
+			bestField, bestScore = field.key, score
+		}
+	}
+
+	return bestField, bestScore
+}
+
+func matchSearchText(value, query string) int {
+	normalized := utils.NormalizeSearchText(value)
+	if normalized == "" {
+		return 0
+	}
+
+	switch {
+	case normalized == query:
+		return searchScoreExact
+	case strings.HasPrefix(normalized, query):
+		return searchScorePrefix
+	}
+
+	for _, token := range utils.SearchTokens(value) {
+		if strings.HasPrefix(token, query) {
+			return searchScoreWordPrefix
+		}
+	}
+
+	if strings.Contains(normalized, query) {
+		return searchScoreSubstring
+	}
+
+	return 0
+}
+
+// ---------------------------------- Find Stations Within -------------------------
+func (s *stationServiceType) FindStationsInBBox(ctx context.Context, bbox [4]float64, limit int) (*StationWithinResponse, error) {
+	return s.findWithin(ctx, bboxShape(bbox), limit)
+}
+
+func (s *stationServiceType) FindStationsWithin(ctx context.Context, geometry GeoJSONGeometry, limit int) (*StationWithinResponse, error) {
+	shape, err := geometry.shape()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findWithin(ctx, shape, limit)
+}
+
+func (s *stationServiceType) findWithin(ctx context.Context, shape *geoWithinShape, limit int) (*StationWithinResponse, error) {
+	if limit < 1 || limit > 5000 {
+		return nil, fmt.Errorf("%w: invalid limit: must be between 1 and 5000", ErrInvalidStation)
+	}
+
+	stations, err := s.repo.FindWithin(ctx, StationWithinRequest{shape: shape, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations within area: %w", err)
+	}
+
+	truncated := len(stations) > limit
+	if truncated {
+		stations = stations[:limit]
+	}
+
+	return &StationWithinResponse{
+		Success:   true,
+		Count:     len(stations),
+		Truncated: truncated,
+		Data:      stations,
+	}, nil
+}
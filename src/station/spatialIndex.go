@@ -0,0 +1,172 @@
+package station
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+// ---------------------------------- KD-Tree -------------------------
+
+// stationIndex is a KD-tree over stations as points on the unit sphere. The
+// straight-line distance between two such points grows with the great circle
+// distance, so nearest neighbours in 3D are nearest by haversine as well,
+// without the special cases latitude and longitude have at the poles and the
+// antimeridian.
+type stationIndex struct {
+	entries []indexEntry
+}
+
+type indexEntry struct {
+	point     [3]float64
+	stationID int
+}
+
+type indexNeighbour struct {
+	stationID int
+	chordSq   float64
+}
+
+func newStationIndex(entries []indexEntry) *stationIndex {
+	buildIndex(entries, 0)
+	return &stationIndex{entries: entries}
+}
+
+// buildIndex orders entries in place so the median of each range splits it on
+// the axis for its depth.
+func buildIndex(entries []indexEntry, depth int) {
+	if len(entries) <= 1 {
+		return
+	}
+
+	axis := depth % 3
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].point[axis] < entries[j].point[axis]
+	})
+
+	mid := len(entries) / 2
+	buildIndex(entries[:mid], depth+1)
+	buildIndex(entries[mid+1:], depth+1)
+}
+
+func (t *stationIndex) Len() int {
+	return len(t.entries)
+}
+
+// nearest returns up to limit stations within maxKm of the point, closest
+// first. Equal distances are ordered by station id.
+func (t *stationIndex) nearest(lat, long float64, limit int, maxKm float64) []indexNeighbour {
+	if limit <= 0 || len(t.entries) == 0 {
+		return nil
+	}
+
+	search := &indexSearch{
+		target:   unitVector(lat, long),
+		limit:    limit,
+		maxDstSq: math.Inf(1),
+	}
+	if maxKm > 0 {
+		search.maxDstSq = chordSquared(maxKm)
+	}
+
+	search.visit(t.entries, 0)
+
+	results := make([]indexNeighbour, len(search.found))
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(&search.found).(indexNeighbour)
+	}
+	return results
+}
+
+type indexSearch struct {
+	target   [3]float64
+	limit    int
+	maxDstSq float64
+	found    neighbourHeap
+}
+
+// bound is the squared distance a candidate has to beat.
+func (s *indexSearch) bound() float64 {
+	if len(s.found) < s.limit {
+		return s.maxDstSq
+	}
+	return math.Min(s.maxDstSq, s.found[0].chordSq)
+}
+
+func (s *indexSearch) visit(entries []indexEntry, depth int) {
+	if len(entries) == 0 {
+		return
+	}
+
+	mid := len(entries) / 2
+	entry := entries[mid]
+
+	candidate := indexNeighbour{stationID: entry.stationID, chordSq: distanceSquared(s.target, entry.point)}
+	if candidate.chordSq <= s.maxDstSq {
+		if len(s.found) < s.limit {
+			heap.Push(&s.found, candidate)
+		} else if candidate.before(s.found[0]) {
+			s.found[0] = candidate
+			heap.Fix(&s.found, 0)
+		}
+	}
+
+	axis := depth % 3
+	diff := s.target[axis] - entry.point[axis]
+
+	near, far := entries[:mid], entries[mid+1:]
+	if diff > 0 {
+		near, far = far, near
+	}
+
+	s.visit(near, depth+1)
+	if diff*diff <= s.bound() {
+		s.visit(far, depth+1)
+	}
+}
+
+func (n indexNeighbour) before(other indexNeighbour) bool {
+	if n.chordSq != other.chordSq {
+		return n.chordSq < other.chordSq
+	}
+	return n.stationID < other.stationID
+}
+
+// neighbourHeap keeps the farthest neighbour found so far on top.
+type neighbourHeap []indexNeighbour
+
+func (h neighbourHeap) Len() int            { return len(h) }
+func (h neighbourHeap) Less(i, j int) bool  { return h[j].before(h[i]) }
+func (h neighbourHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighbourHeap) Push(x interface{}) { *h = append(*h, x.(indexNeighbour)) }
+func (h *neighbourHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+func unitVector(lat, long float64) [3]float64 {
+	latRad := lat * math.Pi / 180
+	longRad := long * math.Pi / 180
+
+	return [3]float64{
+		math.Cos(latRad) * math.Cos(longRad),
+		math.Cos(latRad) * math.Sin(longRad),
+		math.Sin(latRad),
+	}
+}
+
+func distanceSquared(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// chordSquared converts a great circle distance to the squared straight-line
+// distance between points on the unit sphere.
+func chordSquared(km float64) float64 {
+	chord := 2 * math.Sin(math.Min(km/utils.EarthRadiusKm, math.Pi)/2)
+	return chord * chord
+}
@@ -0,0 +1,608 @@
+package station
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sqliteStationRepositoryType stores stations in a local SQLite file. An R*Tree
+// kept up to date by triggers narrows geo queries to a bounding box and the
+// haversine distance decides the result, so nearest results match the memory
+// store.
+type sqliteStationRepositoryType struct {
+	db *sql.DB
+}
+
+func NewSQLiteStationRepository(db *sql.DB) StationRepository {
+	return &sqliteStationRepositoryType{
+		db: db,
+	}
+}
+
+// MigrateSQLite applies the files in migrations/sqlite that are not yet
+// recorded in schema_migrations.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	migrations, err := loadSQLMigrations("sqlite")
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		applied, err := applySQLiteMigration(ctx, db, migration)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		if applied {
+			log.Printf("Applied SQLite migration %s", migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func applySQLiteMigration(ctx context.Context, db *sql.DB, migration sqlMigration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)", migration.Version).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		migration.Version, migration.Name, time.Now().UnixMilli()); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+const sqliteStationColumns = `id, object_id, station_code, name, en_name, th_short, en_short, chname,
+	controldivision, exact_km, exact_distance, km, class, lat, long, location_long, location_lat,
+	active, giveway, dual_track, comment, created_at, updated_at, deleted_at`
+
+// sqliteGeoFilter is the filter every Mongo geo query uses.
+const sqliteGeoFilter = `active = 1 AND ` + sqliteMapFilter
+
+// sqliteMapFilter keeps every located station, active or not.
+const sqliteMapFilter = `location_long IS NOT NULL AND deleted_at IS NULL`
+
+type sqliteScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteStation(row sqliteScanner) (*StationModel, error) {
+	var station StationModel
+	var objectID string
+	var x, y sql.NullFloat64
+	var createdAt, updatedAt int64
+	var deletedAt sql.NullInt64
+
+	err := row.Scan(
+		&station.StationID, &objectID, &station.StationCode, &station.Name, &station.EnName,
+		&station.ThShort, &station.EnShort, &station.ChName,
+		&station.ControlDiv, &station.ExactKM, &station.ExactDistance, &station.KM, &station.Class,
+		&station.Lat, &station.Long, &x, &y,
+		&station.Active, &station.Giveway, &station.DualTrack, &station.Comment,
+		&createdAt, &updatedAt, &deletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	station.ID, _ = primitive.ObjectIDFromHex(objectID)
+	if x.Valid && y.Valid {
+		station.Location = &GeoJSONPointModel{Type: "Point", Coordinates: []float64{x.Float64, y.Float64}}
+	}
+	station.CreatedAt = primitive.DateTime(createdAt)
+	station.UpdatedAt = primitive.DateTime(updatedAt)
+	if deletedAt.Valid {
+		deleted := primitive.DateTime(deletedAt.Int64)
+		station.DeletedAt = &deleted
+	}
+
+	return &station, nil
+}
+
+func (r *sqliteStationRepositoryType) queryStations(ctx context.Context, query string, args ...interface{}) ([]StationModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer rows.Close()
+
+	var stations []StationModel
+	for rows.Next() {
+		station, err := scanSQLiteStation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+		stations = append(stations, *station)
+	}
+
+	return stations, rows.Err()
+}
+
+// sqliteSetArgs are the values stationSetFields writes in Mongo, in the order
+// of sqliteSetColumns.
+func sqliteSetArgs(station StationModel, now primitive.DateTime) []interface{} {
+	var long, lat interface{}
+	if station.Location != nil && len(station.Location.Coordinates) >= 2 {
+		long, lat = station.Location.Coordinates[0], station.Location.Coordinates[1]
+	}
+
+	return []interface{}{
+		station.StationCode, station.Name, station.EnName, station.ThShort, station.EnShort, station.ChName,
+		station.ControlDiv, station.ExactKM, station.ExactDistance, station.KM, station.Class,
+		station.Lat, station.Long, long, lat,
+		station.Active, station.Giveway, station.DualTrack, station.Comment, int64(now),
+	}
+}
+
+const sqliteSetColumns = `station_code = ?, name = ?, en_name = ?, th_short = ?, en_short = ?, chname = ?,
+	controldivision = ?, exact_km = ?, exact_distance = ?, km = ?, class = ?,
+	lat = ?, long = ?, location_long = ?, location_lat = ?,
+	active = ?, giveway = ?, dual_track = ?, comment = ?, updated_at = ?`
+
+// sqliteInsertStation takes the id, object id and creation time followed by
+// sqliteSetArgs.
+const sqliteInsertStation = `INSERT INTO stations (id, object_id, created_at,
+	station_code, name, en_name, th_short, en_short, chname,
+	controldivision, exact_km, exact_distance, km, class,
+	lat, long, location_long, location_lat, active, giveway, dual_track, comment, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func sqliteInsertArgs(station StationModel, objectID primitive.ObjectID, now primitive.DateTime) []interface{} {
+	return append([]interface{}{station.StationID, objectID.Hex(), int64(now)}, sqliteSetArgs(station, now)...)
+}
+
+// ---------------------------------- Upsert Many -------------------------
+func (r *sqliteStationRepositoryType) UpsertMany(ctx context.Context, stations []StationModel) (*StationUpsertResult, error) {
+	if len(stations) == 0 {
+		return &StationUpsertResult{}, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert stations: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	result := &StationUpsertResult{}
+
+	for _, station := range stations {
+		update, err := tx.ExecContext(ctx, `UPDATE stations SET `+sqliteSetColumns+`, deleted_at = NULL WHERE id = ?`,
+			append(sqliteSetArgs(station, now), station.StationID)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert stations: %w", err)
+		}
+
+		if updated, _ := update.RowsAffected(); updated > 0 {
+			result.Updated++
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, sqliteInsertStation, sqliteInsertArgs(station, primitive.NewObjectID(), now)...); err != nil {
+			return nil, fmt.Errorf("failed to insert stations: %w", err)
+		}
+		result.Inserted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to insert stations: %w", err)
+	}
+
+	log.Printf("Successfully processed %d stations: %d inserted, %d updated",
+		len(stations), result.Inserted, result.Updated)
+	return result, nil
+}
+
+// ---------------------------------- Find By ID -------------------------
+func (r *sqliteStationRepositoryType) FindByID(ctx context.Context, stationID int) (*StationModel, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+sqliteStationColumns+` FROM stations WHERE id = ?`, stationID)
+
+	station, err := scanSQLiteStation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station: %w", err)
+	}
+
+	return station, nil
+}
+
+// ---------------------------------- Find By IDs -------------------------
+func (r *sqliteStationRepositoryType) FindByIDs(ctx context.Context, stationIDs []int) ([]StationModel, error) {
+	if len(stationIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(stationIDs)), ",")
+	args := make([]interface{}, len(stationIDs))
+	for i, stationID := range stationIDs {
+		args[i] = stationID
+	}
+
+	return r.queryStations(ctx, `SELECT `+sqliteStationColumns+` FROM stations WHERE id IN (`+placeholders+`)`, args...)
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *sqliteStationRepositoryType) Insert(ctx context.Context, station *StationModel) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	objectID := primitive.NewObjectID()
+
+	result, err := r.db.ExecContext(ctx, strings.Replace(sqliteInsertStation, "INSERT", "INSERT OR IGNORE", 1),
+		sqliteInsertArgs(*station, objectID, now)...)
+	if err != nil {
+		return fmt.Errorf("failed to insert station: %w", err)
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return ErrStationExists
+	}
+
+	station.ID = objectID
+	station.CreatedAt = now
+	station.UpdatedAt = now
+	return nil
+}
+
+// ---------------------------------- Update -------------------------
+func (r *sqliteStationRepositoryType) Update(ctx context.Context, station *StationModel) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.ExecContext(ctx, `UPDATE stations SET `+sqliteSetColumns+` WHERE id = ?`,
+		append(sqliteSetArgs(*station, now), station.StationID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update station: %w", err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return ErrStationNotFound
+	}
+
+	station.UpdatedAt = now
+	return nil
+}
+
+// ---------------------------------- Delete -------------------------
+func (r *sqliteStationRepositoryType) Delete(ctx context.Context, stationID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM stations WHERE id = ?`, stationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete station: %w", err)
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return ErrStationNotFound
+	}
+
+	return nil
+}
+
+// ---------------------------------- Find Search Candidates -------------------------
+func (r *sqliteStationRepositoryType) FindSearchCandidates(ctx context.Context) ([]StationModel, error) {
+	return r.queryStations(ctx, `SELECT `+sqliteStationColumns+` FROM stations WHERE active = 1 AND deleted_at IS NULL`)
+}
+
+// ---------------------------------- Find All -------------------------
+func (r *sqliteStationRepositoryType) FindAll(ctx context.Context) ([]StationModel, error) {
+	return r.queryStations(ctx, `SELECT `+sqliteStationColumns+` FROM stations ORDER BY id`)
+}
+
+// ---------------------------------- Find All IDs -------------------------
+func (r *sqliteStationRepositoryType) FindAllIDs(ctx context.Context) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM stations WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find station ids: %w", err)
+	}
+	defer rows.Close()
+
+	var stationIDs []int
+	for rows.Next() {
+		var stationID int
+		if err := rows.Scan(&stationID); err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+		stationIDs = append(stationIDs, stationID)
+	}
+
+	return stationIDs, rows.Err()
+}
+
+// ---------------------------------- Deactivate Many -------------------------
+func (r *sqliteStationRepositoryType) DeactivateMany(ctx context.Context, stationIDs []int, comment string) error {
+	return r.updateMany(ctx, stationIDs, `active = 0, updated_at = ?1,
+		comment = CASE WHEN comment IN ('', 'NULL') THEN ?2
+			ELSE 'New Comment: ' || ?2 || ' | Original Comment: ' || comment END`,
+		"active <> 0", "failed to deactivate stations", comment)
+}
+
+// ---------------------------------- Soft Delete Many -------------------------
+func (r *sqliteStationRepositoryType) SoftDeleteMany(ctx context.Context, stationIDs []int) error {
+	return r.updateMany(ctx, stationIDs, `deleted_at = ?1, updated_at = ?1`, "", "failed to soft delete stations")
+}
+
+// updateMany runs set on every station matching where in one transaction. ?1
+// is the current time and extra args follow from ?2.
+func (r *sqliteStationRepositoryType) updateMany(ctx context.Context, stationIDs []int, set, where, message string, extra ...interface{}) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	defer tx.Rollback()
+
+	now := int64(primitive.NewDateTimeFromTime(time.Now()))
+	query := fmt.Sprintf("UPDATE stations SET %s WHERE id = ?%d", set, len(extra)+2)
+	if where != "" {
+		query += " AND " + where
+	}
+
+	for _, stationID := range stationIDs {
+		args := append(append([]interface{}{now}, extra...), stationID)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("%s: %w", message, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Spatial Pre-filter -------------------------
+
+type sqliteNeighbour struct {
+	station  StationModel
+	distance float64
+}
+
+// candidatesInBox reads the stations whose R*Tree entry overlaps the box.
+func (r *sqliteStationRepositoryType) candidatesInBox(ctx context.Context, box [4]float64) ([]StationModel, error) {
+	return r.queryStations(ctx, `SELECT `+sqliteStationColumns+` FROM stations
+	WHERE id IN (SELECT id FROM stations_rtree WHERE max_long >= ? AND min_long <= ? AND max_lat >= ? AND min_lat <= ?)
+		AND `+sqliteGeoFilter, box[0], box[2], box[1], box[3])
+}
+
+// searchBox returns minLon,minLat,maxLon,maxLat around a point holding every
+// location within radiusKm. It widens to all longitudes near the poles and
+// across the antimeridian rather than splitting the box.
+func searchBox(lat, long, radiusKm float64) [4]float64 {
+	dLat := radiusKm / utils.EarthRadiusKm * 180 / math.Pi
+	minLat, maxLat := lat-dLat, lat+dLat
+
+	if minLat <= -90 || maxLat >= 90 {
+		return [4]float64{-180, math.Max(minLat, -90), 180, math.Min(maxLat, 90)}
+	}
+
+	// The widest point of the circle is nearer the pole than lat itself
+	widest := math.Max(math.Abs(minLat), math.Abs(maxLat))
+	dLong := dLat / math.Cos(widest*math.Pi/180)
+	if long-dLong < -180 || long+dLong > 180 {
+		return [4]float64{-180, minLat, 180, maxLat}
+	}
+
+	return [4]float64{long - dLong, minLat, long + dLong, maxLat}
+}
+
+// nearest returns at least need stations ordered by distance then id, or all
+// of them when there are fewer. The search box grows until enough stations
+// are inside the circle it is sure to cover. A maxKm above zero stops the
+// search at that distance.
+func (r *sqliteStationRepositoryType) nearest(ctx context.Context, lat, long float64, need int, maxKm float64) ([]sqliteNeighbour, error) {
+	const halfCircumference = math.Pi * utils.EarthRadiusKm
+
+	radius := float64(nearestMaxDistanceKm)
+	if maxKm > 0 {
+		radius = maxKm
+	}
+
+	for {
+		stations, err := r.candidatesInBox(ctx, searchBox(lat, long, radius))
+		if err != nil {
+			return nil, err
+		}
+
+		neighbours := make([]sqliteNeighbour, 0, len(stations))
+		for _, station := range stations {
+			distance := utils.HaversineKm(lat, long, station.Location.Coordinates[1], station.Location.Coordinates[0])
+			if distance <= radius {
+				neighbours = append(neighbours, sqliteNeighbour{station: station, distance: distance})
+			}
+		}
+
+		if len(neighbours) >= need || maxKm > 0 || radius >= halfCircumference {
+			sort.Slice(neighbours, func(i, j int) bool {
+				if neighbours[i].distance != neighbours[j].distance {
+					return neighbours[i].distance < neighbours[j].distance
+				}
+				return neighbours[i].station.StationID < neighbours[j].station.StationID
+			})
+			return neighbours, nil
+		}
+
+		radius = math.Min(radius*4, halfCircumference)
+	}
+}
+
+func sqliteNearestData(neighbours []sqliteNeighbour) []NearestStationData {
+	responses := make([]NearestStationData, len(neighbours))
+	for i, neighbour := range neighbours {
+		responses[i] = NearestStationData{
+			ID:       neighbour.station.StationID,
+			Name:     neighbour.station.Name,
+			EnName:   neighbour.station.EnName,
+			Lat:      neighbour.station.Lat,
+			Long:     neighbour.station.Long,
+			Distance: math.Round(neighbour.distance*1000) / 1000,
+		}
+	}
+	return responses
+}
+
+// ---------------------------------- Find Nearest Station -------------------------
+func (r *sqliteStationRepositoryType) FindNearestStation(ctx context.Context, data NearestStationRequest) ([]NearestStationData, error) {
+	neighbours, err := r.nearest(ctx, data.Lat, data.Long, data.Limit, nearestMaxDistanceKm)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(neighbours) == 0 {
+		return nil, ErrNoStationsNearby
+	}
+
+	return sqliteNearestData(neighbours[:min(len(neighbours), data.Limit)]), nil
+}
+
+// ---------------------------------- Find Nearest Station Pagination -------------------------
+func (r *sqliteStationRepositoryType) FindNearestStationPagination(ctx context.Context, data NearestStationPaginationRequest) ([]NearestStationData, int, error) {
+	var totalItems int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM stations WHERE `+sqliteGeoFilter).Scan(&totalItems); err != nil {
+		return nil, 0, fmt.Errorf("failed to count stations: %w", err)
+	}
+
+	if totalItems == 0 {
+		return nil, 0, fmt.Errorf("no results returned")
+	}
+
+	start := (data.Page - 1) * data.PageSize
+	neighbours, err := r.nearest(ctx, data.Lat, data.Long, start+data.PageSize, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if start >= len(neighbours) {
+		return []NearestStationData{}, totalItems, nil
+	}
+
+	end := min(len(neighbours), start+data.PageSize)
+	return sqliteNearestData(neighbours[start:end]), totalItems, nil
+}
+
+// ---------------------------------- Find Within -------------------------
+func (r *sqliteStationRepositoryType) FindWithin(ctx context.Context, data StationWithinRequest) ([]StationWithinData, error) {
+	stations, err := r.candidatesInBox(ctx, data.shape.bounds())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].StationID < stations[j].StationID
+	})
+
+	responses := make([]StationWithinData, 0)
+	for _, station := range stations {
+		if !data.shape.contains(station.Location.Coordinates[1], station.Location.Coordinates[0]) {
+			continue
+		}
+
+		responses = append(responses, StationWithinData{
+			ID:     station.StationID,
+			Name:   station.Name,
+			EnName: station.EnName,
+			Lat:    station.Lat,
+			Long:   station.Long,
+		})
+		if len(responses) > data.Limit {
+			break
+		}
+	}
+
+	return responses, nil
+}
+
+// ---------------------------------- Find Map Points -------------------------
+func (r *sqliteStationRepositoryType) FindMapPoints(ctx context.Context) ([]StationMapPoint, error) {
+	stations, err := r.queryStations(ctx, `SELECT `+sqliteStationColumns+` FROM stations WHERE `+sqliteMapFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]StationMapPoint, len(stations))
+	for i, station := range stations {
+		points[i] = StationMapPoint{
+			StationID: station.StationID,
+			Name:      station.Name,
+			EnName:    station.EnName,
+			Lat:       station.Lat,
+			Long:      station.Long,
+			Class:     station.Class,
+			Active:    station.Active,
+			DualTrack: station.DualTrack,
+			Location:  station.Location,
+		}
+	}
+
+	return points, nil
+}
+
+// ---------------------------------- Each Export Station -------------------------
+func (r *sqliteStationRepositoryType) EachExportStation(ctx context.Context, data StationExportRequest, fn func(StationModel) error) error {
+	if data.Lat != nil && data.Long != nil {
+		neighbours, err := r.nearest(ctx, *data.Lat, *data.Long, 0, data.RadiusKm)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(neighbours, func(i, j int) bool {
+			return neighbours[i].station.StationID < neighbours[j].station.StationID
+		})
+
+		for _, neighbour := range neighbours {
+			if err := fn(neighbour.station); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sqliteStationColumns+` FROM stations WHERE `+sqliteGeoFilter+` ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		station, err := scanSQLiteStation(rows)
+		if err != nil {
+			return fmt.Errorf("failed to decode results: %w", err)
+		}
+
+		if err := fn(*station); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
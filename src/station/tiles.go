@@ -0,0 +1,114 @@
+package station
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zombox0633/go_spinsoft/src/mvt"
+)
+
+const (
+	stationTileLayer = "stations"
+	maxTileZoom      = 22
+	maxCachedTiles   = 4096
+	// tileBuffer keeps symbols near an edge from being cut off
+	tileBuffer = 64
+)
+
+type tileKey struct {
+	z, x, y int
+}
+
+// tileAt returns the cached tile or encodes it from the cached points.
+func (c *stationMapCache) tileAt(ctx context.Context, repo StationRepository, key tileKey) ([]byte, error) {
+	c.mu.Lock()
+	tile, ok := c.tiles[key]
+	c.mu.Unlock()
+	if ok {
+		return tile, nil
+	}
+
+	points, generation, err := c.load(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	tile, err = encodeStationTile(points, key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Tiles encoded from points an update has since replaced are not kept
+	if c.generation != generation {
+		return tile, nil
+	}
+
+	// A full cache is dropped rather than tracking usage
+	if len(c.tiles) >= maxCachedTiles {
+		c.tiles = make(map[tileKey][]byte)
+	}
+	c.tiles[key] = tile
+
+	return tile, nil
+}
+
+func encodeStationTile(points []StationMapPoint, key tileKey) ([]byte, error) {
+	layer := mvt.Layer{
+		Name:   stationTileLayer,
+		Extent: mvt.DefaultExtent,
+	}
+
+	for _, point := range points {
+		if point.Location == nil || len(point.Location.Coordinates) < 2 {
+			continue
+		}
+
+		long, lat := point.Location.Coordinates[0], point.Location.Coordinates[1]
+		x, y := mvt.TileCoordinates(lat, long, key.z, key.x, key.y, layer.Extent)
+		if x < -tileBuffer || y < -tileBuffer || x > layer.Extent+tileBuffer || y > layer.Extent+tileBuffer {
+			continue
+		}
+
+		layer.Features = append(layer.Features, mvt.Feature{
+			ID: uint64(point.StationID),
+			X:  x,
+			Y:  y,
+			Properties: map[string]interface{}{
+				"id":         point.StationID,
+				"name":       point.Name,
+				"en_name":    point.EnName,
+				"class":      point.Class,
+				"active":     point.Active,
+				"dual_track": point.DualTrack,
+			},
+		})
+	}
+
+	if len(layer.Features) == 0 {
+		return []byte{}, nil
+	}
+
+	return mvt.Encode(layer)
+}
+
+// ---------------------------------- Station Tile -------------------------
+func (s *stationServiceType) StationTile(ctx context.Context, z, x, y int) ([]byte, error) {
+	if z < 0 || z > maxTileZoom {
+		return nil, fmt.Errorf("%w: invalid zoom: must be between 0 and %d", ErrInvalidStation, maxTileZoom)
+	}
+
+	n := 1 << z
+	if x < 0 || x >= n || y < 0 || y >= n {
+		return nil, fmt.Errorf("%w: tile %d/%d/%d is out of range", ErrInvalidStation, z, x, y)
+	}
+
+	tile, err := s.mapCache.tileAt(ctx, s.repo, tileKey{z: z, x: x, y: y})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tile: %w", err)
+	}
+
+	return tile, nil
+}
@@ -0,0 +1,173 @@
+package timetable
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TimetableControllerType struct {
+	service TimetableService
+}
+
+func NewTimetableController(service TimetableService) *TimetableControllerType {
+	return &TimetableControllerType{
+		service: service,
+	}
+}
+
+// ---------------------------------- Post Import Timetable -------------------------
+func (c *TimetableControllerType) PostImportTimetable(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required file: file")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Failed to open uploaded file")
+	}
+	defer file.Close()
+
+	result, err := c.service.ImportFeed(ctx.Context(), file, fileHeader.Size)
+	if err != nil {
+		return timetableError(err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(result)
+}
+
+// ---------------------------------- Get Station Departures -------------------------
+func (c *TimetableControllerType) GetStationDepartures(ctx *fiber.Ctx) error {
+	stationID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid station id")
+	}
+
+	at, err := ParseDepartureTime(ctx.Query("time"), time.Now())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid time: must be RFC3339, YYYY-MM-DDTHH:MM or HH:MM")
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "10"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	req := DeparturesRequest{
+		StationID: stationID,
+		At:        at,
+		Limit:     limit,
+	}
+
+	result, err := c.service.GetStationDepartures(ctx.Context(), req)
+	if err != nil {
+		return timetableError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Nearest Departures -------------------------
+func (c *TimetableControllerType) GetNearestDepartures(ctx *fiber.Ctx) error {
+	latStr := ctx.Query("lat")
+	longStr := ctx.Query("long")
+
+	if latStr == "" || longStr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Missing required parameters: lat and long")
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid latitude")
+	}
+
+	long, err := strconv.ParseFloat(longStr, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid longitude")
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "3"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+	}
+
+	departures, err := strconv.Atoi(ctx.Query("departures", "5"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid departures")
+	}
+
+	at, err := ParseDepartureTime(ctx.Query("time"), time.Now())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid time: must be RFC3339, YYYY-MM-DDTHH:MM or HH:MM")
+	}
+
+	req := NearestDeparturesRequest{
+		Lat:        lat,
+		Long:       long,
+		Limit:      limit,
+		Departures: departures,
+		At:         at,
+	}
+
+	result, err := c.service.FindNearestDepartures(ctx.Context(), req)
+	if err != nil {
+		return timetableError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ---------------------------------- Get Trip -------------------------
+func (c *TimetableControllerType) GetTrip(ctx *fiber.Ctx) error {
+	result, err := c.service.GetTrip(ctx.Context(), ctx.Params("tripId"))
+	if err != nil {
+		return timetableError(err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// ParseDepartureTime reads a departure query time. Values without a zone are
+// in ServiceLocation, and a bare clock time is on the same day as now.
+func ParseDepartureTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, ServiceLocation); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		clock, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+
+		today := now.In(ServiceLocation)
+		return time.Date(today.Year(), today.Month(), today.Day(),
+			clock.Hour(), clock.Minute(), clock.Second(), 0, ServiceLocation), nil
+	}
+
+	return time.Time{}, errors.New("invalid time")
+}
+
+func timetableError(err error) error {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidFeed), errors.Is(err, ErrInvalidTimetableRequest):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
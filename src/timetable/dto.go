@@ -0,0 +1,95 @@
+package timetable
+
+import (
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/station"
+)
+
+// Timetable Import
+type TimetableImportResponse struct {
+	Success       bool     `json:"success"`
+	Agencies      int      `json:"agencies"`
+	Routes        int      `json:"routes"`
+	Trips         int      `json:"trips"`
+	StopTimes     int      `json:"stop_times"`
+	Calendars     int      `json:"calendars"`
+	CalendarDates int      `json:"calendar_dates"`
+	Stops         int      `json:"stops"`
+	LinkedStops   int      `json:"linked_stops"`
+	UnlinkedStops []string `json:"unlinked_stops"`
+	UnlinkedTotal int      `json:"unlinked_total"`
+	Message       string   `json:"message"`
+	DurationMs    int64    `json:"duration_ms"`
+}
+
+// Departures
+type DeparturesRequest struct {
+	StationID int
+	At        time.Time
+	Limit     int
+}
+
+type DeparturesResponse struct {
+	Success   bool            `json:"success"`
+	StationID int             `json:"station_id"`
+	At        time.Time       `json:"at"`
+	Data      []DepartureData `json:"data"`
+}
+
+type DepartureData struct {
+	TripID         string    `json:"trip_id"`
+	RouteID        string    `json:"route_id"`
+	RouteShortName string    `json:"route_short_name"`
+	RouteLongName  string    `json:"route_long_name"`
+	Headsign       string    `json:"headsign"`
+	StationID      int       `json:"station_id"`
+	StopID         string    `json:"stop_id"`
+	StopSequence   int       `json:"stop_sequence"`
+	DepartureTime  string    `json:"departure_time"`
+	ServiceDate    string    `json:"service_date"`
+	DepartsAt      time.Time `json:"departs_at"`
+}
+
+// Nearest Departures
+type NearestDeparturesRequest struct {
+	Lat        float64
+	Long       float64
+	Limit      int
+	Departures int
+	At         time.Time
+}
+
+type NearestDeparturesResponse struct {
+	Success bool                    `json:"success"`
+	At      time.Time               `json:"at"`
+	Data    []NearestDeparturesData `json:"data"`
+}
+
+type NearestDeparturesData struct {
+	station.NearestStationData
+	Departures []DepartureData `json:"departures"`
+}
+
+// Trip Detail
+type TripResponse struct {
+	Success bool            `json:"success"`
+	Data    *TripDetailData `json:"data"`
+}
+
+type TripDetailData struct {
+	TripModel
+	Route *RouteModel    `json:"route"`
+	Stops []TripStopData `json:"stops"`
+}
+
+type TripStopData struct {
+	StopSequence  int     `json:"stop_sequence"`
+	StopID        string  `json:"stop_id"`
+	StationID     int     `json:"station_id"`
+	Name          string  `json:"name"`
+	Lat           float64 `json:"lat"`
+	Long          float64 `json:"long"`
+	ArrivalTime   string  `json:"arrival_time"`
+	DepartureTime string  `json:"departure_time"`
+}
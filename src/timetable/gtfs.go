@@ -0,0 +1,137 @@
+package timetable
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFeed = errors.New("invalid GTFS feed")
+
+type gtfsFeed struct {
+	files map[string]*zip.File
+}
+
+func openGTFSFeed(r io.ReaderAt, size int64) (*gtfsFeed, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open zip: %v", ErrInvalidFeed, err)
+	}
+
+	feed := &gtfsFeed{files: make(map[string]*zip.File)}
+	for _, file := range archive.File {
+		feed.files[path.Base(file.Name)] = file
+	}
+
+	return feed, nil
+}
+
+type gtfsRow struct {
+	index  map[string]int
+	record []string
+}
+
+func (r gtfsRow) get(column string) string {
+	i, ok := r.index[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r gtfsRow) getInt(column string) int {
+	value, _ := strconv.Atoi(r.get(column))
+	return value
+}
+
+func (r gtfsRow) getFloat(column string) float64 {
+	value, _ := strconv.ParseFloat(r.get(column), 64)
+	return value
+}
+
+// each streams the rows of a feed file to fn. Optional files that are missing
+// are skipped.
+func (f *gtfsFeed) each(name string, required bool, columns []string, fn func(gtfsRow) error) error {
+	file, ok := f.files[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("%w: %s not found in zip", ErrInvalidFeed, name)
+		}
+		return nil
+	}
+
+	entry, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer entry.Close()
+
+	reader := csv.NewReader(entry)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to read %s header: %v", ErrInvalidFeed, name, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		index[strings.TrimSpace(column)] = i
+	}
+
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return fmt.Errorf("%w: %s is missing column %q", ErrInvalidFeed, name, column)
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse %s line %d: %v", ErrInvalidFeed, name, line, err)
+		}
+
+		if err := fn(gtfsRow{index: index, record: record}); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+	}
+}
+
+// parseGTFSTime converts H:MM:SS to seconds after midnight. Hours may be 24 or
+// more for trips that run past midnight. Blank times return -1.
+func parseGTFSTime(value string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("%w: invalid time %q", ErrInvalidFeed, value)
+	}
+
+	var fields [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && n > 59) {
+			return 0, fmt.Errorf("%w: invalid time %q", ErrInvalidFeed, value)
+		}
+		fields[i] = n
+	}
+
+	return fields[0]*3600 + fields[1]*60 + fields[2], nil
+}
@@ -0,0 +1,374 @@
+package timetable
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	stopTimeBatchSize   = 5000
+	unlinkedReportLimit = 100
+)
+
+// gtfsTables holds every feed file except stop_times, which is streamed.
+type gtfsTables struct {
+	agencies      []AgencyModel
+	routes        []RouteModel
+	trips         map[string]TripModel
+	tripOrder     []string
+	calendars     []CalendarModel
+	calendarDates []CalendarDateModel
+	stops         map[string]StopModel
+	stopOrder     []string
+}
+
+// ---------------------------------- Import Feed -------------------------
+
+// ImportFeed replaces the stored timetable with a GTFS zip. The whole feed is
+// checked first, then loaded into staging collections that only replace the
+// live timetable once every insert has succeeded.
+func (s *timetableServiceType) ImportFeed(ctx context.Context, r io.ReaderAt, size int64) (_ *TimetableImportResponse, err error) {
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
+
+	startedAt := time.Now()
+
+	feed, err := openGTFSFeed(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	stationIDs, stationCodes, err := s.repo.FindStationKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := readGTFSTables(feed, stationIDs, stationCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// First pass only validates stop_times
+	stopTimeCount, err := eachStopTime(feed, tables, func(StopTimeModel) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.BeginImport(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.repo.DiscardImport(context.WithoutCancel(ctx))
+		}
+	}()
+
+	trips := make([]TripModel, 0, len(tables.tripOrder))
+	for _, tripID := range tables.tripOrder {
+		trips = append(trips, tables.trips[tripID])
+	}
+
+	stops := make([]StopModel, 0, len(tables.stopOrder))
+	for _, stopID := range tables.stopOrder {
+		stops = append(stops, tables.stops[stopID])
+	}
+
+	inserts := []func() error{
+		func() error { return s.repo.InsertAgencies(ctx, tables.agencies) },
+		func() error { return s.repo.InsertRoutes(ctx, tables.routes) },
+		func() error { return s.repo.InsertTrips(ctx, trips) },
+		func() error { return s.repo.InsertCalendars(ctx, tables.calendars) },
+		func() error { return s.repo.InsertCalendarDates(ctx, tables.calendarDates) },
+		func() error { return s.repo.InsertStops(ctx, stops) },
+	}
+	for _, insert := range inserts {
+		if err := insert(); err != nil {
+			return nil, err
+		}
+	}
+
+	batch := make([]StopTimeModel, 0, stopTimeBatchSize)
+	_, err = eachStopTime(feed, tables, func(stopTime StopTimeModel) error {
+		batch = append(batch, stopTime)
+		if len(batch) < stopTimeBatchSize {
+			return nil
+		}
+
+		err := s.repo.InsertStopTimes(ctx, batch)
+		batch = batch[:0]
+		return err
+	})
+	if err == nil {
+		err = s.repo.InsertStopTimes(ctx, batch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to import stop times: %w", err)
+	}
+
+	// A cancelled request must not stop the swap half way through
+	if err := s.repo.CommitImport(context.WithoutCancel(ctx)); err != nil {
+		return nil, err
+	}
+
+	response := &TimetableImportResponse{
+		Success:       true,
+		Agencies:      len(tables.agencies),
+		Routes:        len(tables.routes),
+		Trips:         len(trips),
+		StopTimes:     stopTimeCount,
+		Calendars:     len(tables.calendars),
+		CalendarDates: len(tables.calendarDates),
+		Stops:         len(stops),
+		UnlinkedStops: []string{},
+	}
+
+	for _, stop := range stops {
+		if stop.StationID > 0 {
+			response.LinkedStops++
+			continue
+		}
+
+		response.UnlinkedTotal++
+		if len(response.UnlinkedStops) < unlinkedReportLimit {
+			response.UnlinkedStops = append(response.UnlinkedStops, stop.StopID)
+		}
+	}
+
+	response.Message = fmt.Sprintf("Imported %d trips and %d stop times, %d of %d stops linked to stations",
+		response.Trips, response.StopTimes, response.LinkedStops, response.Stops)
+	response.DurationMs = time.Since(startedAt).Milliseconds()
+
+	return response, nil
+}
+
+func readGTFSTables(feed *gtfsFeed, stationIDs map[int]bool, stationCodes map[int]int) (*gtfsTables, error) {
+	tables := &gtfsTables{
+		trips: make(map[string]TripModel),
+		stops: make(map[string]StopModel),
+	}
+
+	err := feed.each("agency.txt", true, []string{"agency_name", "agency_timezone"}, func(row gtfsRow) error {
+		tables.agencies = append(tables.agencies, AgencyModel{
+			AgencyID: row.get("agency_id"),
+			Name:     row.get("agency_name"),
+			URL:      row.get("agency_url"),
+			Timezone: row.get("agency_timezone"),
+			Lang:     row.get("agency_lang"),
+			Phone:    row.get("agency_phone"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	routeIDs := make(map[string]bool)
+	err = feed.each("routes.txt", true, []string{"route_id", "route_type"}, func(row gtfsRow) error {
+		route := RouteModel{
+			RouteID:   row.get("route_id"),
+			AgencyID:  row.get("agency_id"),
+			ShortName: row.get("route_short_name"),
+			LongName:  row.get("route_long_name"),
+			Type:      row.getInt("route_type"),
+			Color:     row.get("route_color"),
+			TextColor: row.get("route_text_color"),
+		}
+		if route.RouteID == "" {
+			return fmt.Errorf("%w: route_id is required", ErrInvalidFeed)
+		}
+
+		routeIDs[route.RouteID] = true
+		tables.routes = append(tables.routes, route)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceIDs := make(map[string]bool)
+	err = feed.each("calendar.txt", false, []string{"service_id", "start_date", "end_date"}, func(row gtfsRow) error {
+		calendar := CalendarModel{
+			ServiceID: row.get("service_id"),
+			Monday:    row.get("monday") == "1",
+			Tuesday:   row.get("tuesday") == "1",
+			Wednesday: row.get("wednesday") == "1",
+			Thursday:  row.get("thursday") == "1",
+			Friday:    row.get("friday") == "1",
+			Saturday:  row.get("saturday") == "1",
+			Sunday:    row.get("sunday") == "1",
+			StartDate: row.get("start_date"),
+			EndDate:   row.get("end_date"),
+		}
+		for _, date := range []string{calendar.StartDate, calendar.EndDate} {
+			if _, err := time.Parse(gtfsDateLayout, date); err != nil {
+				return fmt.Errorf("%w: invalid date %q", ErrInvalidFeed, date)
+			}
+		}
+
+		serviceIDs[calendar.ServiceID] = true
+		tables.calendars = append(tables.calendars, calendar)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = feed.each("calendar_dates.txt", false, []string{"service_id", "date", "exception_type"}, func(row gtfsRow) error {
+		date := CalendarDateModel{
+			ServiceID:     row.get("service_id"),
+			Date:          row.get("date"),
+			ExceptionType: row.getInt("exception_type"),
+		}
+		if _, err := time.Parse(gtfsDateLayout, date.Date); err != nil {
+			return fmt.Errorf("%w: invalid date %q", ErrInvalidFeed, date.Date)
+		}
+		if date.ExceptionType != ServiceAdded && date.ExceptionType != ServiceRemoved {
+			return fmt.Errorf("%w: exception_type must be 1 or 2", ErrInvalidFeed)
+		}
+
+		serviceIDs[date.ServiceID] = true
+		tables.calendarDates = append(tables.calendarDates, date)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serviceIDs) == 0 {
+		return nil, fmt.Errorf("%w: calendar.txt or calendar_dates.txt is required", ErrInvalidFeed)
+	}
+
+	err = feed.each("trips.txt", true, []string{"route_id", "service_id", "trip_id"}, func(row gtfsRow) error {
+		trip := TripModel{
+			TripID:      row.get("trip_id"),
+			RouteID:     row.get("route_id"),
+			ServiceID:   row.get("service_id"),
+			Headsign:    row.get("trip_headsign"),
+			ShortName:   row.get("trip_short_name"),
+			DirectionID: row.getInt("direction_id"),
+		}
+		if !routeIDs[trip.RouteID] {
+			return fmt.Errorf("%w: trip %q has unknown route_id %q", ErrInvalidFeed, trip.TripID, trip.RouteID)
+		}
+		if !serviceIDs[trip.ServiceID] {
+			return fmt.Errorf("%w: trip %q has unknown service_id %q", ErrInvalidFeed, trip.TripID, trip.ServiceID)
+		}
+		if _, ok := tables.trips[trip.TripID]; ok {
+			return fmt.Errorf("%w: duplicate trip_id %q", ErrInvalidFeed, trip.TripID)
+		}
+
+		tables.trips[trip.TripID] = trip
+		tables.tripOrder = append(tables.tripOrder, trip.TripID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = feed.each("stops.txt", true, []string{"stop_id"}, func(row gtfsRow) error {
+		stop := StopModel{
+			StopID:        row.get("stop_id"),
+			StopCode:      row.get("stop_code"),
+			Name:          row.get("stop_name"),
+			Lat:           row.getFloat("stop_lat"),
+			Long:          row.getFloat("stop_lon"),
+			ParentStation: row.get("parent_station"),
+		}
+		stop.StationID = linkStation(stop, stationIDs, stationCodes)
+
+		tables.stops[stop.StopID] = stop
+		tables.stopOrder = append(tables.stopOrder, stop.StopID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Platforms that did not match a station use their parent station's link
+	for _, stopID := range tables.stopOrder {
+		stop := tables.stops[stopID]
+		if stop.StationID > 0 || stop.ParentStation == "" {
+			continue
+		}
+
+		stop.StationID = tables.stops[stop.ParentStation].StationID
+		tables.stops[stopID] = stop
+	}
+
+	return tables, nil
+}
+
+// linkStation matches stop_id against station id then station code, and
+// finally stop_code against station code.
+func linkStation(stop StopModel, stationIDs map[int]bool, stationCodes map[int]int) int {
+	if n, err := strconv.Atoi(stop.StopID); err == nil {
+		if stationIDs[n] {
+			return n
+		}
+		if stationID, ok := stationCodes[n]; ok {
+			return stationID
+		}
+	}
+
+	if n, err := strconv.Atoi(stop.StopCode); err == nil {
+		if stationID, ok := stationCodes[n]; ok {
+			return stationID
+		}
+	}
+
+	return 0
+}
+
+// eachStopTime streams stop_times.txt with the trip fields filled in.
+func eachStopTime(feed *gtfsFeed, tables *gtfsTables, fn func(StopTimeModel) error) (int, error) {
+	count := 0
+
+	columns := []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"}
+	err := feed.each("stop_times.txt", true, columns, func(row gtfsRow) error {
+		trip, ok := tables.trips[row.get("trip_id")]
+		if !ok {
+			return fmt.Errorf("%w: unknown trip_id %q", ErrInvalidFeed, row.get("trip_id"))
+		}
+
+		stop, ok := tables.stops[row.get("stop_id")]
+		if !ok {
+			return fmt.Errorf("%w: unknown stop_id %q", ErrInvalidFeed, row.get("stop_id"))
+		}
+
+		arrivalSecs, err := parseGTFSTime(row.get("arrival_time"))
+		if err != nil {
+			return err
+		}
+		departureSecs, err := parseGTFSTime(row.get("departure_time"))
+		if err != nil {
+			return err
+		}
+
+		headsign := trip.Headsign
+		if stopHeadsign := row.get("stop_headsign"); stopHeadsign != "" {
+			headsign = stopHeadsign
+		}
+
+		count++
+		return fn(StopTimeModel{
+			TripID:        trip.TripID,
+			RouteID:       trip.RouteID,
+			ServiceID:     trip.ServiceID,
+			Headsign:      headsign,
+			StopID:        stop.StopID,
+			StationID:     stop.StationID,
+			StopSequence:  row.getInt("stop_sequence"),
+			ArrivalTime:   row.get("arrival_time"),
+			DepartureTime: row.get("departure_time"),
+			ArrivalSecs:   arrivalSecs,
+			DepartureSecs: departureSecs,
+			PickupType:    row.getInt("pickup_type"),
+			DropOffType:   row.getInt("drop_off_type"),
+		})
+	})
+
+	return count, err
+}
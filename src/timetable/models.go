@@ -0,0 +1,97 @@
+package timetable
+
+// Models mirror the GTFS static files. Times are kept as the feed strings and
+// as seconds after midnight of the service day, which may pass 24:00:00.
+
+type AgencyModel struct {
+	AgencyID string `bson:"agency_id" json:"agency_id"`
+	Name     string `bson:"name" json:"name"`
+	URL      string `bson:"url" json:"url"`
+	Timezone string `bson:"timezone" json:"timezone"`
+	Lang     string `bson:"lang" json:"lang"`
+	Phone    string `bson:"phone" json:"phone"`
+}
+
+type RouteModel struct {
+	RouteID   string `bson:"route_id" json:"route_id"`
+	AgencyID  string `bson:"agency_id" json:"agency_id"`
+	ShortName string `bson:"short_name" json:"short_name"`
+	LongName  string `bson:"long_name" json:"long_name"`
+	Type      int    `bson:"type" json:"type"`
+	Color     string `bson:"color" json:"color"`
+	TextColor string `bson:"text_color" json:"text_color"`
+}
+
+type TripModel struct {
+	TripID      string `bson:"trip_id" json:"trip_id"`
+	RouteID     string `bson:"route_id" json:"route_id"`
+	ServiceID   string `bson:"service_id" json:"service_id"`
+	Headsign    string `bson:"headsign" json:"headsign"`
+	ShortName   string `bson:"short_name" json:"short_name"`
+	DirectionID int    `bson:"direction_id" json:"direction_id"`
+}
+
+// StopTimeModel carries the route, service and headsign of its trip so
+// departures can be read without a join.
+type StopTimeModel struct {
+	TripID        string `bson:"trip_id" json:"trip_id"`
+	RouteID       string `bson:"route_id" json:"route_id"`
+	ServiceID     string `bson:"service_id" json:"service_id"`
+	Headsign      string `bson:"headsign" json:"headsign"`
+	StopID        string `bson:"stop_id" json:"stop_id"`
+	StationID     int    `bson:"station_id" json:"station_id"`
+	StopSequence  int    `bson:"stop_sequence" json:"stop_sequence"`
+	ArrivalTime   string `bson:"arrival_time" json:"arrival_time"`
+	DepartureTime string `bson:"departure_time" json:"departure_time"`
+	ArrivalSecs   int    `bson:"arrival_secs" json:"-"`
+	DepartureSecs int    `bson:"departure_secs" json:"-"`
+	PickupType    int    `bson:"pickup_type" json:"pickup_type"`
+	DropOffType   int    `bson:"drop_off_type" json:"drop_off_type"`
+}
+
+type CalendarModel struct {
+	ServiceID string `bson:"service_id" json:"service_id"`
+	Monday    bool   `bson:"monday" json:"monday"`
+	Tuesday   bool   `bson:"tuesday" json:"tuesday"`
+	Wednesday bool   `bson:"wednesday" json:"wednesday"`
+	Thursday  bool   `bson:"thursday" json:"thursday"`
+	Friday    bool   `bson:"friday" json:"friday"`
+	Saturday  bool   `bson:"saturday" json:"saturday"`
+	Sunday    bool   `bson:"sunday" json:"sunday"`
+	StartDate string `bson:"start_date" json:"start_date"`
+	EndDate   string `bson:"end_date" json:"end_date"`
+}
+
+const (
+	ServiceAdded   = 1
+	ServiceRemoved = 2
+)
+
+type CalendarDateModel struct {
+	ServiceID     string `bson:"service_id" json:"service_id"`
+	Date          string `bson:"date" json:"date"`
+	ExceptionType int    `bson:"exception_type" json:"exception_type"`
+}
+
+// StopModel links a GTFS stop to a station. StationID is 0 when no station
+// matched.
+type StopModel struct {
+	StopID        string  `bson:"stop_id" json:"stop_id"`
+	StopCode      string  `bson:"stop_code" json:"stop_code"`
+	Name          string  `bson:"name" json:"name"`
+	Lat           float64 `bson:"lat" json:"lat"`
+	Long          float64 `bson:"long" json:"long"`
+	ParentStation string  `bson:"parent_station" json:"parent_station"`
+	StationID     int     `bson:"station_id" json:"station_id"`
+}
+
+// runsOn reports whether the weekly pattern covers date, a GTFS YYYYMMDD
+// string on the given weekday.
+func (c *CalendarModel) runsOn(date string, weekday int) bool {
+	if date < c.StartDate || date > c.EndDate {
+		return false
+	}
+
+	days := [7]bool{c.Sunday, c.Monday, c.Tuesday, c.Wednesday, c.Thursday, c.Friday, c.Saturday}
+	return days[weekday]
+}
@@ -0,0 +1,380 @@
+package timetable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrTripNotFound = errors.New("trip not found")
+
+const (
+	agencyCollection       = "gtfs_agency"
+	routeCollection        = "gtfs_routes"
+	tripCollection         = "gtfs_trips"
+	stopTimeCollection     = "gtfs_stop_times"
+	calendarCollection     = "gtfs_calendar"
+	calendarDateCollection = "gtfs_calendar_dates"
+	stopCollection         = "gtfs_stops"
+	stationCollection      = "stations"
+
+	// Imports load into <collection>_staging and rename over the live
+	// collection once every file is in.
+	stagingSuffix = "_staging"
+)
+
+var timetableCollections = []string{
+	agencyCollection,
+	routeCollection,
+	tripCollection,
+	stopTimeCollection,
+	calendarCollection,
+	calendarDateCollection,
+	stopCollection,
+}
+
+type TimetableRepository interface {
+	BeginImport(ctx context.Context) error
+	CommitImport(ctx context.Context) error
+	DiscardImport(ctx context.Context) error
+	InsertAgencies(ctx context.Context, agencies []AgencyModel) error
+	InsertRoutes(ctx context.Context, routes []RouteModel) error
+	InsertTrips(ctx context.Context, trips []TripModel) error
+	InsertStopTimes(ctx context.Context, stopTimes []StopTimeModel) error
+	InsertCalendars(ctx context.Context, calendars []CalendarModel) error
+	InsertCalendarDates(ctx context.Context, dates []CalendarDateModel) error
+	InsertStops(ctx context.Context, stops []StopModel) error
+	FindStationKeys(ctx context.Context) (map[int]bool, map[int]int, error)
+	FindCalendars(ctx context.Context) ([]CalendarModel, error)
+	FindCalendarDates(ctx context.Context, date string) ([]CalendarDateModel, error)
+	FindDepartures(ctx context.Context, stationIDs []int, serviceIDs []string, fromSecs int, limit int) ([]StopTimeModel, error)
+	FindTrip(ctx context.Context, tripID string) (*TripModel, error)
+	FindRoutes(ctx context.Context, routeIDs []string) ([]RouteModel, error)
+	FindStopTimesByTrip(ctx context.Context, tripID string) ([]StopTimeModel, error)
+	FindStops(ctx context.Context, stopIDs []string) ([]StopModel, error)
+}
+
+type timetableRepositoryType struct {
+	db *mongo.Database
+}
+
+func NewTimetableRepository(db *mongo.Database) TimetableRepository {
+	return &timetableRepositoryType{
+		db: db,
+	}
+}
+
+// ---------------------------------- Staging -------------------------
+
+// BeginImport recreates empty staging collections, dropping any left behind
+// by an import that did not finish. Insert methods write to these.
+func (r *timetableRepositoryType) BeginImport(ctx context.Context) error {
+	if err := r.DiscardImport(ctx); err != nil {
+		return err
+	}
+
+	// Created up front so every rename in CommitImport has a source, even
+	// for optional files the feed left out
+	for _, name := range timetableCollections {
+		if err := r.db.CreateCollection(ctx, name+stagingSuffix); err != nil {
+			return fmt.Errorf("failed to create %s%s: %w", name, stagingSuffix, err)
+		}
+	}
+
+	return nil
+}
+
+// CommitImport copies the live indexes onto the staging collections and
+// renames each one over its live collection.
+func (r *timetableRepositoryType) CommitImport(ctx context.Context) error {
+	for _, name := range timetableCollections {
+		if err := r.copyIndexes(ctx, name, name+stagingSuffix); err != nil {
+			return err
+		}
+	}
+
+	admin := r.db.Client().Database("admin")
+	for _, name := range timetableCollections {
+		command := bson.D{
+			{Key: "renameCollection", Value: r.db.Name() + "." + name + stagingSuffix},
+			{Key: "to", Value: r.db.Name() + "." + name},
+			{Key: "dropTarget", Value: true},
+		}
+		if err := admin.RunCommand(ctx, command).Err(); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// DiscardImport drops the staging collections.
+func (r *timetableRepositoryType) DiscardImport(ctx context.Context) error {
+	for _, name := range timetableCollections {
+		if err := r.db.Collection(name + stagingSuffix).Drop(ctx); err != nil {
+			return fmt.Errorf("failed to drop %s%s: %w", name, stagingSuffix, err)
+		}
+	}
+
+	return nil
+}
+
+// copyIndexes recreates the indexes the migrations put on the live collection,
+// which the rename would otherwise drop along with it.
+func (r *timetableRepositoryType) copyIndexes(ctx context.Context, from, to string) error {
+	specs, err := r.db.Collection(from).Indexes().ListSpecifications(ctx)
+	if err != nil {
+		var commandErr mongo.CommandError
+		if errors.As(err, &commandErr) && commandErr.Code == 26 {
+			return nil // NamespaceNotFound
+		}
+		return fmt.Errorf("failed to list %s indexes: %w", from, err)
+	}
+
+	var models []mongo.IndexModel
+	for _, spec := range specs {
+		if spec.Name == "_id_" {
+			continue
+		}
+
+		indexOptions := options.Index().SetName(spec.Name)
+		if spec.Unique != nil {
+			indexOptions.SetUnique(*spec.Unique)
+		}
+		if spec.Sparse != nil {
+			indexOptions.SetSparse(*spec.Sparse)
+		}
+		if spec.ExpireAfterSeconds != nil {
+			indexOptions.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+		}
+
+		models = append(models, mongo.IndexModel{Keys: spec.KeysDocument, Options: indexOptions})
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.Collection(to).Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to index %s: %w", to, err)
+	}
+
+	return nil
+}
+
+// ---------------------------------- Insert -------------------------
+func (r *timetableRepositoryType) InsertAgencies(ctx context.Context, agencies []AgencyModel) error {
+	return r.insertMany(ctx, agencyCollection+stagingSuffix, documents(agencies))
+}
+
+func (r *timetableRepositoryType) InsertRoutes(ctx context.Context, routes []RouteModel) error {
+	return r.insertMany(ctx, routeCollection+stagingSuffix, documents(routes))
+}
+
+func (r *timetableRepositoryType) InsertTrips(ctx context.Context, trips []TripModel) error {
+	return r.insertMany(ctx, tripCollection+stagingSuffix, documents(trips))
+}
+
+func (r *timetableRepositoryType) InsertStopTimes(ctx context.Context, stopTimes []StopTimeModel) error {
+	return r.insertMany(ctx, stopTimeCollection+stagingSuffix, documents(stopTimes))
+}
+
+func (r *timetableRepositoryType) InsertCalendars(ctx context.Context, calendars []CalendarModel) error {
+	return r.insertMany(ctx, calendarCollection+stagingSuffix, documents(calendars))
+}
+
+func (r *timetableRepositoryType) InsertCalendarDates(ctx context.Context, dates []CalendarDateModel) error {
+	return r.insertMany(ctx, calendarDateCollection+stagingSuffix, documents(dates))
+}
+
+func (r *timetableRepositoryType) InsertStops(ctx context.Context, stops []StopModel) error {
+	return r.insertMany(ctx, stopCollection+stagingSuffix, documents(stops))
+}
+
+func (r *timetableRepositoryType) insertMany(ctx context.Context, name string, values []interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.Collection(name).InsertMany(ctx, values, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func documents[T any](items []T) []interface{} {
+	values := make([]interface{}, len(items))
+	for i := range items {
+		values[i] = items[i]
+	}
+	return values
+}
+
+// ---------------------------------- Find Station Keys -------------------------
+
+// FindStationKeys returns the ids of non-deleted stations and a map of
+// station code to station id, used to link GTFS stops to stations.
+func (r *timetableRepositoryType) FindStationKeys(ctx context.Context) (map[int]bool, map[int]int, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": false}}
+	projection := bson.M{"_id": 0, "id": 1, "station_code": 1}
+
+	cursor, err := r.db.Collection(stationCollection).Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find stations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	ids := make(map[int]bool)
+	codes := make(map[int]int)
+	for cursor.Next(ctx) {
+		var station struct {
+			StationID   int `bson:"id"`
+			StationCode int `bson:"station_code"`
+		}
+		if err := cursor.Decode(&station); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode station: %w", err)
+		}
+
+		ids[station.StationID] = true
+		if station.StationCode > 0 {
+			codes[station.StationCode] = station.StationID
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read stations: %w", err)
+	}
+
+	return ids, codes, nil
+}
+
+// ---------------------------------- Calendar -------------------------
+func (r *timetableRepositoryType) FindCalendars(ctx context.Context) ([]CalendarModel, error) {
+	cursor, err := r.db.Collection(calendarCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calendars: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var calendars []CalendarModel
+	if err := cursor.All(ctx, &calendars); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return calendars, nil
+}
+
+func (r *timetableRepositoryType) FindCalendarDates(ctx context.Context, date string) ([]CalendarDateModel, error) {
+	cursor, err := r.db.Collection(calendarDateCollection).Find(ctx, bson.M{"date": date})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calendar dates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var dates []CalendarDateModel
+	if err := cursor.All(ctx, &dates); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return dates, nil
+}
+
+// ---------------------------------- Find Departures -------------------------
+
+// FindDepartures returns stop times at the stations that leave at or after
+// fromSecs on the given services. Stops where passengers cannot board are
+// left out.
+func (r *timetableRepositoryType) FindDepartures(ctx context.Context, stationIDs []int, serviceIDs []string, fromSecs int, limit int) ([]StopTimeModel, error) {
+	if len(stationIDs) == 0 || len(serviceIDs) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"station_id":     bson.M{"$in": stationIDs},
+		"service_id":     bson.M{"$in": serviceIDs},
+		"departure_secs": bson.M{"$gte": fromSecs},
+		"pickup_type":    bson.M{"$ne": 1},
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "departure_secs", Value: 1}, {Key: "trip_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.Collection(stopTimeCollection).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find departures: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stopTimes []StopTimeModel
+	if err := cursor.All(ctx, &stopTimes); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stopTimes, nil
+}
+
+// ---------------------------------- Find Trip -------------------------
+func (r *timetableRepositoryType) FindTrip(ctx context.Context, tripID string) (*TripModel, error) {
+	var trip TripModel
+
+	err := r.db.Collection(tripCollection).FindOne(ctx, bson.M{"trip_id": tripID}).Decode(&trip)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTripNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trip: %w", err)
+	}
+
+	return &trip, nil
+}
+
+func (r *timetableRepositoryType) FindRoutes(ctx context.Context, routeIDs []string) ([]RouteModel, error) {
+	cursor, err := r.db.Collection(routeCollection).Find(ctx, bson.M{"route_id": bson.M{"$in": routeIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find routes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var routes []RouteModel
+	if err := cursor.All(ctx, &routes); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return routes, nil
+}
+
+func (r *timetableRepositoryType) FindStopTimesByTrip(ctx context.Context, tripID string) ([]StopTimeModel, error) {
+	findOptions := options.Find().SetSort(bson.M{"stop_sequence": 1})
+
+	cursor, err := r.db.Collection(stopTimeCollection).Find(ctx, bson.M{"trip_id": tripID}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stop times: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stopTimes []StopTimeModel
+	if err := cursor.All(ctx, &stopTimes); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stopTimes, nil
+}
+
+func (r *timetableRepositoryType) FindStops(ctx context.Context, stopIDs []string) ([]StopModel, error) {
+	cursor, err := r.db.Collection(stopCollection).Find(ctx, bson.M{"stop_id": bson.M{"$in": stopIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stops: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stops []StopModel
+	if err := cursor.All(ctx, &stops); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	return stops, nil
+}
@@ -0,0 +1,22 @@
+package timetable
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/zombox0633/go_spinsoft/src/station"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TimetableRoutes(api fiber.Router, DB *mongo.Database, stationRepo station.StationRepository) {
+	timetableRepo := NewTimetableRepository(DB)
+
+	timetableService := NewTimetableService(timetableRepo, stationRepo)
+	timetableController := NewTimetableController(timetableService)
+
+	timetableGroup := api.Group("/timetable")
+
+	timetableGroup.Post("/import", timetableController.PostImportTimetable)
+	timetableGroup.Get("/trip/:tripId", timetableController.GetTrip)
+
+	api.Get("/station/nearest/departures", timetableController.GetNearestDepartures)
+	api.Get("/station/:id<int>/departures", timetableController.GetStationDepartures)
+}
@@ -0,0 +1,339 @@
+package timetable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zombox0633/go_spinsoft/src/station"
+	"github.com/zombox0633/go_spinsoft/src/utils"
+)
+
+var ErrInvalidTimetableRequest = errors.New("invalid timetable request")
+
+const gtfsDateLayout = "20060102"
+
+// ServiceLocation is the timezone departure times are read in. It falls back
+// to a fixed UTC+7 zone when the system has no tz database.
+var ServiceLocation = loadServiceLocation("Asia/Bangkok")
+
+func loadServiceLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone("ICT", 7*60*60)
+	}
+	return location
+}
+
+type TimetableService interface {
+	ImportFeed(ctx context.Context, r io.ReaderAt, size int64) (*TimetableImportResponse, error)
+	GetStationDepartures(ctx context.Context, req DeparturesRequest) (*DeparturesResponse, error)
+	NextDepartures(ctx context.Context, stationIDs []int, at time.Time, limit int) (map[int][]DepartureData, error)
+	FindNearestDepartures(ctx context.Context, req NearestDeparturesRequest) (*NearestDeparturesResponse, error)
+	GetTrip(ctx context.Context, tripID string) (*TripResponse, error)
+}
+
+type timetableServiceType struct {
+	repo        TimetableRepository
+	stationRepo station.StationRepository
+	importMu    sync.Mutex
+}
+
+func NewTimetableService(repo TimetableRepository, stationRepo station.StationRepository) TimetableService {
+	return &timetableServiceType{
+		repo:        repo,
+		stationRepo: stationRepo,
+	}
+}
+
+// ---------------------------------- Get Station Departures -------------------------
+func (s *timetableServiceType) GetStationDepartures(ctx context.Context, req DeparturesRequest) (*DeparturesResponse, error) {
+	if req.Limit < 1 || req.Limit > 50 {
+		return nil, fmt.Errorf("%w: invalid limit: must be between 1 and 50", ErrInvalidTimetableRequest)
+	}
+
+	departures, err := s.NextDepartures(ctx, []int{req.StationID}, req.At, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	data := departures[req.StationID]
+	if data == nil {
+		data = []DepartureData{}
+	}
+
+	return &DeparturesResponse{
+		Success:   true,
+		StationID: req.StationID,
+		At:        req.At.In(ServiceLocation),
+		Data:      data,
+	}, nil
+}
+
+// ---------------------------------- Next Departures -------------------------
+
+// NextDepartures returns up to limit departures per station at or after at.
+// Trips still running from the previous service day and the first trips of
+// the next day are included, so results are correct around midnight.
+func (s *timetableServiceType) NextDepartures(ctx context.Context, stationIDs []int, at time.Time, limit int) (map[int][]DepartureData, error) {
+	at = at.In(ServiceLocation)
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, ServiceLocation)
+	secs := int(at.Sub(today).Seconds())
+
+	calendars, err := s.repo.FindCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each service day is searched from the same instant
+	serviceDays := []struct {
+		date     time.Time
+		fromSecs int
+	}{
+		{today.AddDate(0, 0, -1), secs + 24*60*60},
+		{today, secs},
+		{today.AddDate(0, 0, 1), 0},
+	}
+
+	results := make(map[int][]DepartureData, len(stationIDs))
+	routeIDs := make(map[string]bool)
+
+	for _, day := range serviceDays {
+		serviceIDs, err := s.activeServices(ctx, calendars, day.date)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stationID := range stationIDs {
+			stopTimes, err := s.repo.FindDepartures(ctx, []int{stationID}, serviceIDs, day.fromSecs, limit)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, stopTime := range stopTimes {
+				routeIDs[stopTime.RouteID] = true
+				results[stationID] = append(results[stationID], DepartureData{
+					TripID:        stopTime.TripID,
+					RouteID:       stopTime.RouteID,
+					Headsign:      stopTime.Headsign,
+					StationID:     stopTime.StationID,
+					StopID:        stopTime.StopID,
+					StopSequence:  stopTime.StopSequence,
+					DepartureTime: stopTime.DepartureTime,
+					ServiceDate:   day.date.Format("2006-01-02"),
+					DepartsAt:     serviceTime(day.date, stopTime.DepartureSecs),
+				})
+			}
+		}
+	}
+
+	routes, err := s.findRoutes(ctx, routeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for stationID, departures := range results {
+		sort.SliceStable(departures, func(i, j int) bool {
+			return departures[i].DepartsAt.Before(departures[j].DepartsAt)
+		})
+		if len(departures) > limit {
+			departures = departures[:limit]
+		}
+
+		for i := range departures {
+			if route, ok := routes[departures[i].RouteID]; ok {
+				departures[i].RouteShortName = route.ShortName
+				departures[i].RouteLongName = route.LongName
+			}
+		}
+		results[stationID] = departures
+	}
+
+	return results, nil
+}
+
+// ---------------------------------- Find Nearest Departures -------------------------
+func (s *timetableServiceType) FindNearestDepartures(ctx context.Context, req NearestDeparturesRequest) (*NearestDeparturesResponse, error) {
+	if err := utils.ValidateCoordinates(req.Lat, req.Long); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTimetableRequest, err)
+	}
+
+	if req.Limit < 1 || req.Limit > 10 {
+		return nil, fmt.Errorf("%w: invalid limit: must be between 1 and 10", ErrInvalidTimetableRequest)
+	}
+
+	if req.Departures < 1 || req.Departures > 20 {
+		return nil, fmt.Errorf("%w: invalid departures: must be between 1 and 20", ErrInvalidTimetableRequest)
+	}
+
+	stations, err := s.stationRepo.FindNearestStation(ctx, station.NearestStationRequest{
+		Lat:   req.Lat,
+		Long:  req.Long,
+		Limit: req.Limit,
+	})
+	if errors.Is(err, station.ErrNoStationsNearby) {
+		return &NearestDeparturesResponse{
+			Success: true,
+			At:      req.At.In(ServiceLocation),
+			Data:    []NearestDeparturesData{},
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find nearest station: %w", err)
+	}
+
+	stationIDs := make([]int, 0, len(stations))
+	for _, nearest := range stations {
+		stationIDs = append(stationIDs, nearest.ID)
+	}
+
+	departures, err := s.NextDepartures(ctx, stationIDs, req.At, req.Departures)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]NearestDeparturesData, 0, len(stations))
+	for _, nearest := range stations {
+		stationDepartures := departures[nearest.ID]
+		if stationDepartures == nil {
+			stationDepartures = []DepartureData{}
+		}
+
+		data = append(data, NearestDeparturesData{
+			NearestStationData: nearest,
+			Departures:         stationDepartures,
+		})
+	}
+
+	return &NearestDeparturesResponse{
+		Success: true,
+		At:      req.At.In(ServiceLocation),
+		Data:    data,
+	}, nil
+}
+
+// activeServices applies calendar_dates exceptions on top of the weekly
+// calendar for one service day.
+func (s *timetableServiceType) activeServices(ctx context.Context, calendars []CalendarModel, date time.Time) ([]string, error) {
+	day := date.Format(gtfsDateLayout)
+
+	active := make(map[string]bool)
+	for _, calendar := range calendars {
+		if calendar.runsOn(day, int(date.Weekday())) {
+			active[calendar.ServiceID] = true
+		}
+	}
+
+	exceptions, err := s.repo.FindCalendarDates(ctx, day)
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		switch exception.ExceptionType {
+		case ServiceAdded:
+			active[exception.ServiceID] = true
+		case ServiceRemoved:
+			delete(active, exception.ServiceID)
+		}
+	}
+
+	serviceIDs := make([]string, 0, len(active))
+	for serviceID := range active {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+
+	return serviceIDs, nil
+}
+
+func (s *timetableServiceType) findRoutes(ctx context.Context, routeIDs map[string]bool) (map[string]RouteModel, error) {
+	if len(routeIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(routeIDs))
+	for routeID := range routeIDs {
+		ids = append(ids, routeID)
+	}
+
+	routes, err := s.repo.FindRoutes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]RouteModel, len(routes))
+	for _, route := range routes {
+		byID[route.RouteID] = route
+	}
+
+	return byID, nil
+}
+
+// serviceTime resolves seconds after midnight on a service day. GTFS counts
+// from noon minus 12h, which is midnight in a zone without daylight saving.
+func serviceTime(date time.Time, secs int) time.Time {
+	return date.Add(time.Duration(secs) * time.Second)
+}
+
+// ---------------------------------- Get Trip -------------------------
+func (s *timetableServiceType) GetTrip(ctx context.Context, tripID string) (*TripResponse, error) {
+	trip, err := s.repo.FindTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopTimes, err := s.repo.FindStopTimesByTrip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopIDs := make([]string, 0, len(stopTimes))
+	for _, stopTime := range stopTimes {
+		stopIDs = append(stopIDs, stopTime.StopID)
+	}
+
+	stops, err := s.repo.FindStops(ctx, stopIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stopsByID := make(map[string]StopModel, len(stops))
+	for _, stop := range stops {
+		stopsByID[stop.StopID] = stop
+	}
+
+	detail := &TripDetailData{
+		TripModel: *trip,
+		Stops:     make([]TripStopData, 0, len(stopTimes)),
+	}
+
+	routes, err := s.findRoutes(ctx, map[string]bool{trip.RouteID: true})
+	if err != nil {
+		return nil, err
+	}
+	if route, ok := routes[trip.RouteID]; ok {
+		detail.Route = &route
+	}
+
+	for _, stopTime := range stopTimes {
+		stop := stopsByID[stopTime.StopID]
+		detail.Stops = append(detail.Stops, TripStopData{
+			StopSequence:  stopTime.StopSequence,
+			StopID:        stopTime.StopID,
+			StationID:     stopTime.StationID,
+			Name:          stop.Name,
+			Lat:           stop.Lat,
+			Long:          stop.Long,
+			ArrivalTime:   stopTime.ArrivalTime,
+			DepartureTime: stopTime.DepartureTime,
+		})
+	}
+
+	return &TripResponse{
+		Success: true,
+		Data:    detail,
+	}, nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "math"
+
+// ---------------------------------- Great Circle Distance -------------------------
+
+// EarthRadiusKm matches the radius MongoDB uses for spherical $geoNear queries.
+const EarthRadiusKm = 6378.1
+
+func HaversineKm(lat1, long1, lat2, long2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLong := toRadians(long2 - long1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLong/2)*math.Sin(dLong/2)
+
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func toRadians(degree float64) float64 {
+	return degree * math.Pi / 180
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ---------------------------------- Search Text -------------------------
+
+// nikhahit + sara aa (typed in either order) -> sara am
+var thaiSaraAmReplacer = strings.NewReplacer("\u0e4d\u0e32", "\u0e33", "\u0e32\u0e4d", "\u0e33")
+
+// NormalizeSearchText lowercases the text, drops Thai tone marks, whitespace
+// and punctuation so that typed queries can be compared with station names.
+func NormalizeSearchText(text string) string {
+	text = thaiSaraAmReplacer.Replace(text)
+
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isThaiToneMark(r):
+			continue
+		case unicode.IsSpace(r), unicode.IsPunct(r):
+			continue
+		default:
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
+
+// SearchTokens splits the text on whitespace and normalizes each word.
+func SearchTokens(text string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		if token := NormalizeSearchText(field); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func isThaiToneMark(r rune) bool {
+	return r >= '\u0e48' && r <= '\u0e4b'
+}
@@ -76,3 +76,42 @@ func ToString(value interface{}) string {
 func stringFormat(datatype string) string {
 	return strings.TrimSpace(strings.ReplaceAll(datatype, ",", ""))
 }
+
+// IsInt reports whether ToInt reads value exactly, without truncating a
+// fraction or falling back to 0.
+// Missing and empty values are treated as valid.
+func IsInt(value interface{}) bool {
+	switch v := value.(type) {
+	case nil, int, int32, int64:
+		return true
+	case float64:
+		return v == float64(int(v))
+	case string:
+		v = stringFormat(v)
+		if v == "" {
+			return true
+		}
+		_, err := strconv.Atoi(v)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// IsFloat reports whether ToFloat64 can read value without falling back to 0.
+// Missing and empty values are treated as valid.
+func IsFloat(value interface{}) bool {
+	switch v := value.(type) {
+	case nil, float64, float32, int, int32, int64:
+		return true
+	case string:
+		v = stringFormat(v)
+		if v == "" {
+			return true
+		}
+		_, err := strconv.ParseFloat(v, 64)
+		return err == nil
+	default:
+		return false
+	}
+}